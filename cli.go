@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"straenge-riddle-worker/m/convert"
+	"straenge-riddle-worker/m/models"
+	"straenge-riddle-worker/m/random"
+)
+
+// runCommand runs the offline command given on the command line and returns the exit code.
+func runCommand(args []string) int {
+	switch args[0] {
+	case "generate":
+		return runGenerate(args[1:])
+	case "validate":
+		return runValidate(args[1:])
+	case "stats":
+		return runStats(args[1:])
+	case "lint":
+		return runLint(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, available commands: generate, validate, stats, lint\n", args[0])
+		return 2
+	}
+}
+
+// runGenerate generates a riddle from a concept file without Redis, using the same pipeline as the worker.
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	conceptPath := flags.String("concept", "-", "riddle concept JSON file, - for stdin")
+	outPath := flags.String("out", "-", "output file for the generated riddle config, - for stdout")
+	timeout := flags.Duration("timeout", 60*time.Second, "time limit for the generation")
+	parallelCount := flags.Int("parallel", runtime.NumCPU(), "number of parallel generation attempts")
+	seed := flags.Int64("seed", 0, "seed of the first attempt to reproduce a riddle, 0 for a random seed")
+	dictionaryPath := flags.String("dictionary", "", "optional word list to report bonus words on the board")
+	blocklistPath := flags.String("blocklist", "", "optional word list of words the board must not contain")
+	wordLengths := wordLengthFlags(flags)
+	engine := flags.String("engine", engineBacktracking, "generation engine, backtracking or exact-cover")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	options, err := loadGenerationOptions(*parallelCount, *dictionaryPath, *blocklistPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Word list could not be read: %v\n", err)
+		return 1
+	}
+	options.DefaultWordLengths = *wordLengths
+	options.Engine = *engine
+
+	var riddleConcept models.RiddleConcept
+	if err := readJsonFile(*conceptPath, &riddleConcept); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Riddle concept could not be read: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	var seedPtr *int64
+	if *seed != 0 {
+		seedPtr = seed
+		options.Reproduce = true
+	}
+	result, err := generateFromConcept(ctx, riddleConcept, seedPtr, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Riddle generation failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✅ Riddle generated with seed %d\n", result.Seed)
+	if len(result.BonusWords) > 0 {
+		fmt.Fprintf(os.Stderr, "Bonus words: %s\n", strings.Join(result.BonusWords, ", "))
+	}
+
+	output := convert.TransformToOutputFormat(result.Riddle, riddleConcept.ThemeDescription)
+	if err := writeJsonFile(*outPath, output); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Output could not be written: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runValidate checks riddle config files (or directories of them) and fails if any of them has problems.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: validate <riddle config file or directory>...")
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	riddleConfigs, err := loadRiddleConfigs(flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Riddle configs could not be read: %v\n", err)
+		return 1
+	}
+
+	invalidCount := 0
+	for _, riddleConfig := range riddleConfigs {
+		problems, err := models.ValidateRiddleConfig(ctx, riddleConfig.RiddleConfig)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Validation aborted: %v\n", err)
+			return 1
+		}
+		if len(problems) == 0 {
+			fmt.Printf("✅ %s\n", riddleConfig.FilePath)
+			continue
+		}
+		invalidCount++
+		fmt.Printf("❌ %s\n", riddleConfig.FilePath)
+		for _, problem := range problems {
+			fmt.Printf("   - %s\n", problem)
+		}
+	}
+	fmt.Printf("%d of %d riddle configs valid\n", len(riddleConfigs)-invalidCount, len(riddleConfigs))
+	if invalidCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runLint checks riddle concept files before generation and fails if any of them has lint errors.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	wordLengths := wordLengthFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: lint [flags] <riddle concept file>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	failedCount := 0
+	for _, path := range flags.Args() {
+		var riddleConcept models.RiddleConcept
+		if err := readJsonFile(path, &riddleConcept); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Riddle concept could not be read: %v\n", err)
+			return 1
+		}
+		findings := models.LintConcept(riddleConcept, *wordLengths)
+		switch {
+		case models.HasLintErrors(findings):
+			failedCount++
+			fmt.Printf("❌ %s\n", path)
+		case len(findings) > 0:
+			fmt.Printf("⚠️ %s\n", path)
+		default:
+			fmt.Printf("✅ %s\n", path)
+		}
+		for _, finding := range findings {
+			fmt.Printf("   - %s\n", finding)
+		}
+	}
+	if failedCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runStats generates a riddle from a concept several times and reports success rate, time to riddle and failure types.
+func runStats(args []string) int {
+	flags := flag.NewFlagSet("stats", flag.ContinueOnError)
+	conceptPath := flags.String("concept", "-", "riddle concept JSON file, - for stdin")
+	runs := flags.Int("runs", 20, "number of generations")
+	timeout := flags.Duration("timeout", 60*time.Second, "time limit for each generation")
+	parallelCount := flags.Int("parallel", runtime.NumCPU(), "number of parallel generation attempts")
+	seed := flags.Int64("seed", 0, "seed for the seeds of the generations to repeat a series, 0 for a random seed")
+	dictionaryPath := flags.String("dictionary", "", "optional word list to report bonus words on the board")
+	blocklistPath := flags.String("blocklist", "", "optional word list of words the board must not contain")
+	wordLengths := wordLengthFlags(flags)
+	engine := flags.String("engine", engineBacktracking, "generation engine, backtracking or exact-cover")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *runs < 1 {
+		fmt.Fprintln(os.Stderr, "❌ At least one run is required")
+		return 2
+	}
+
+	options, err := loadGenerationOptions(*parallelCount, *dictionaryPath, *blocklistPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Word list could not be read: %v\n", err)
+		return 1
+	}
+	options.DefaultWordLengths = *wordLengths
+	options.Engine = *engine
+
+	var riddleConcept models.RiddleConcept
+	if err := readJsonFile(*conceptPath, &riddleConcept); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Riddle concept could not be read: %v\n", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	if *seed == 0 {
+		*seed = random.NewSeed()
+	}
+	fmt.Fprintf(os.Stderr, "Running %d generations with seed %d\n", *runs, *seed)
+	// the seeds of the runs must not be derived like attempt seeds, otherwise runs would repeat attempts of each other
+	seeds := random.NewSeededRand(*seed)
+
+	stats := newGenerationStats()
+	for run := 0; run < *runs && ctx.Err() == nil; run++ {
+		runSeed := seeds.Int63()
+		runCtx, cancel := context.WithTimeout(ctx, *timeout)
+		startedAt := time.Now()
+		_, err := generateFromConcept(runCtx, riddleConcept, &runSeed, options)
+		duration := time.Since(startedAt)
+		cancel()
+		if ctx.Err() != nil {
+			// interrupted, the run did not fail on its own
+			break
+		}
+		stats.add(duration, err)
+	}
+
+	stats.write(os.Stdout)
+	return 0
+}
+
+// wordLengthFlags registers the word length limits used for concepts that do not set their own.
+func wordLengthFlags(flags *flag.FlagSet) *models.WordLengths {
+	wordLengths := &models.WordLengths{}
+	flags.IntVar(&wordLengths.Min, "min-word-length", models.DefaultMinWordLength, "minimum number of letters of pool words, unless the concept sets one")
+	flags.IntVar(&wordLengths.Max, "max-word-length", 0, "maximum number of letters of pool words unless the concept sets one, 0 for no limit")
+	return wordLengths
+}
+
+// loadGenerationOptions prepares the generation options of an offline command, word lists with an empty path are not used.
+func loadGenerationOptions(parallelCount int, dictionaryPath, blocklistPath string) (generationOptions, error) {
+	options := generationOptions{ParallelCount: parallelCount}
+	var err error
+	if dictionaryPath != "" {
+		if options.Dictionary, err = models.LoadDictionary(dictionaryPath); err != nil {
+			return options, err
+		}
+	}
+	if blocklistPath != "" {
+		if options.Blocklist, err = models.LoadDictionary(blocklistPath); err != nil {
+			return options, err
+		}
+	}
+	return options, nil
+}
+
+// loadRiddleConfigs reads the given riddle config files, directories are searched for .json files recursively.
+func loadRiddleConfigs(paths []string) ([]models.RiddleConfigFromFile, error) {
+	var riddleConfigs []models.RiddleConfigFromFile
+	for _, path := range paths {
+		err := filepath.WalkDir(path, func(filePath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || (filePath != path && filepath.Ext(filePath) != ".json") {
+				return nil
+			}
+			var riddleConfig models.RiddleConfig
+			if err := readJsonFile(filePath, &riddleConfig); err != nil {
+				return fmt.Errorf("%s: %v", filePath, err)
+			}
+			riddleConfigs = append(riddleConfigs, models.RiddleConfigFromFile{FilePath: filePath, RiddleConfig: &riddleConfig})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return riddleConfigs, nil
+}
+
+func readJsonFile(path string, target any) error {
+	var reader io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+	return json.NewDecoder(reader).Decode(target)
+}
+
+func writeJsonFile(path string, value any) error {
+	var writer io.Writer = os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer = file
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
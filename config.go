@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+type workerConfig struct {
+	RedisUrl       string
+	WorkerID       string
+	HTTPAddr       string
+	ParallelCount  int
+	JobConcurrency int
+	AttemptBudget  int
+	PollInterval   time.Duration
+	ShutdownGrace  time.Duration
+	LeaseTtl       time.Duration
+	ReaperInterval time.Duration
+	Retry          retryPolicy
+	Dictionary     *models.Dictionary
+	Blocklist      *models.Dictionary
+	WordLengths    models.WordLengths
+}
+
+func loadConfig() *workerConfig {
+	redisUrl, success := os.LookupEnv("REDIS_URL")
+	if !success {
+		logrus.Fatal("REDIS_URL not set")
+	}
+
+	parallelCountStr, success := os.LookupEnv("PARALLEL_COUNT")
+	if !success {
+		logrus.Fatal("PARALLEL_COUNT not set")
+	}
+	parallelCount, err := strconv.Atoi(parallelCountStr)
+	if err != nil {
+		logrus.Fatalf("Invalid PARALLEL_COUNT value: %v", err)
+	}
+
+	jobTimeoutStr, success := os.LookupEnv("JOB_TIMEOUT_SECONDS")
+	if !success {
+		logrus.Fatal("JOB_TIMEOUT_SECONDS not set")
+	}
+	jobTimeout, err := strconv.Atoi(jobTimeoutStr)
+	if err != nil {
+		logrus.Fatalf("Invalid JOB_TIMEOUT_SECONDS value: %v", err)
+	}
+
+	// RETRY_TIMEOUT_SECONDS is either a single timeout for all retries
+	// or a comma separated schedule for the 2nd, 3rd, ... attempt, where the last entry repeats
+	retryTimeoutStr, success := os.LookupEnv("RETRY_TIMEOUT_SECONDS")
+	if !success {
+		logrus.Fatal("RETRY_TIMEOUT_SECONDS not set")
+	}
+	timeouts := []time.Duration{time.Duration(jobTimeout) * time.Second}
+	for _, retryTimeoutPart := range strings.Split(retryTimeoutStr, ",") {
+		retryTimeout, err := strconv.Atoi(strings.TrimSpace(retryTimeoutPart))
+		if err != nil {
+			logrus.Fatalf("Invalid RETRY_TIMEOUT_SECONDS value: %v", err)
+		}
+		timeouts = append(timeouts, time.Duration(retryTimeout)*time.Second)
+	}
+
+	// by default, all concurrent jobs share the attempt goroutines of a single job
+	jobConcurrency := optionalEnvInt("JOB_CONCURRENCY", 1)
+	attemptBudget := optionalEnvInt("ATTEMPT_BUDGET", max(parallelCount, 1))
+	if jobConcurrency <= 0 || attemptBudget <= 0 {
+		logrus.Fatal("JOB_CONCURRENCY and ATTEMPT_BUDGET must be positive")
+	}
+
+	workerID, success := os.LookupEnv("WORKER_ID")
+	if !success {
+		workerID = defaultWorkerID()
+	}
+	// monitoring endpoints, an empty address disables the HTTP server
+	httpAddr, success := os.LookupEnv("HTTP_ADDR")
+	if !success {
+		httpAddr = ":8080"
+	}
+
+	leaseTtl := optionalEnvInt("LEASE_TTL_SECONDS", 60)
+	reaperInterval := optionalEnvInt("REAPER_INTERVAL_SECONDS", 30)
+	if leaseTtl <= 0 || reaperInterval <= 0 {
+		logrus.Fatal("LEASE_TTL_SECONDS and REAPER_INTERVAL_SECONDS must be positive")
+	}
+
+	pollInterval := optionalEnvInt("POLL_INTERVAL_SECONDS", 10)
+	if pollInterval <= 0 {
+		logrus.Fatal("POLL_INTERVAL_SECONDS must be positive")
+	}
+
+	shutdownGrace := optionalEnvInt("SHUTDOWN_GRACE_SECONDS", 20)
+	if shutdownGrace < 0 {
+		logrus.Fatal("SHUTDOWN_GRACE_SECONDS must not be negative")
+	}
+
+	maxAttempts := optionalEnvInt("MAX_ATTEMPTS", 2)
+	if maxAttempts <= 0 {
+		logrus.Fatal("MAX_ATTEMPTS must be positive")
+	}
+	retryDelay := optionalEnvInt("RETRY_DELAY_SECONDS", 0)
+	retryBackoffFactor := optionalEnvFloat("RETRY_BACKOFF_FACTOR", 1)
+	if retryDelay < 0 || retryBackoffFactor < 1 {
+		logrus.Fatal("RETRY_DELAY_SECONDS must not be negative and RETRY_BACKOFF_FACTOR must be at least 1")
+	}
+
+	// word length limits for concepts that do not set their own, a maximum of 0 means no limit
+	wordLengths := models.WordLengths{
+		Min: optionalEnvInt("MIN_WORD_LENGTH", models.DefaultMinWordLength),
+		Max: optionalEnvInt("MAX_WORD_LENGTH", 0),
+	}
+	if !wordLengths.Valid() {
+		logrus.Fatal("MIN_WORD_LENGTH must be at least 2 and MAX_WORD_LENGTH 0 or at least MIN_WORD_LENGTH")
+	}
+
+	// optional word lists, see generationOptions
+	dictionary := optionalWordList("DICTIONARY_FILE")
+	blocklist := optionalWordList("BLOCKLIST_FILE")
+
+	return &workerConfig{
+		RedisUrl:       redisUrl,
+		WorkerID:       workerID,
+		HTTPAddr:       httpAddr,
+		ParallelCount:  parallelCount,
+		JobConcurrency: jobConcurrency,
+		AttemptBudget:  attemptBudget,
+		PollInterval:   time.Duration(pollInterval) * time.Second,
+		ShutdownGrace:  time.Duration(shutdownGrace) * time.Second,
+		LeaseTtl:       time.Duration(leaseTtl) * time.Second,
+		ReaperInterval: time.Duration(reaperInterval) * time.Second,
+		Retry: retryPolicy{
+			MaxAttempts:   maxAttempts,
+			Timeouts:      timeouts,
+			Delay:         time.Duration(retryDelay) * time.Second,
+			BackoffFactor: retryBackoffFactor,
+		},
+		Dictionary:  dictionary,
+		Blocklist:   blocklist,
+		WordLengths: wordLengths,
+	}
+}
+
+// optionalEnvInt reads an integer setting from the environment, falling back to a default if unset.
+func optionalEnvInt(name string, fallback int) int {
+	valueStr, success := os.LookupEnv(name)
+	if !success {
+		return fallback
+	}
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		logrus.Fatalf("Invalid %s value: %v", name, err)
+	}
+	return value
+}
+
+// optionalWordList loads the word list file named by an environment variable, nil if the variable is unset.
+func optionalWordList(name string) *models.Dictionary {
+	path, success := os.LookupEnv(name)
+	if !success || path == "" {
+		return nil
+	}
+	dictionary, err := models.LoadDictionary(path)
+	if err != nil {
+		logrus.Fatalf("Invalid %s: %v", name, err)
+	}
+	logrus.Infof("Loaded %d words from %s", dictionary.Len(), path)
+	return dictionary
+}
+
+// optionalEnvFloat reads a decimal setting from the environment, falling back to a default if unset.
+func optionalEnvFloat(name string, fallback float64) float64 {
+	valueStr, success := os.LookupEnv(name)
+	if !success {
+		return fallback
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		logrus.Fatalf("Invalid %s value: %v", name, err)
+	}
+	return value
+}
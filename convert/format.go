@@ -8,12 +8,12 @@ func TransformToOutputFormat(riddle *models.Riddle, theme string) *models.Riddle
 	var riddleConfig = models.RiddleConfig{
 		ConfigVersion: 3,
 		Theme:         theme,
-		Letters:       make([][]string, models.RiddleHeight),
+		Letters:       make([][]string, riddle.Height),
 		Solutions:     []models.SolutionConfig{},
 	}
-	for i := 0; i < models.RiddleHeight; i++ {
-		riddleConfig.Letters[i] = make([]string, models.RiddleWidth)
-		for j := 0; j < models.RiddleWidth; j++ {
+	for i := 0; i < riddle.Height; i++ {
+		riddleConfig.Letters[i] = make([]string, riddle.Width)
+		for j := 0; j < riddle.Width; j++ {
 			var node = riddle.GetNode(i, j)
 			if node.RiddleWord == nil {
 				riddleConfig.Letters[i][j] = " "
@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"straenge-riddle-worker/m/models"
+	"straenge-riddle-worker/m/random"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestOutputFormatRoundTrip converts generated riddles of the sample concepts to the output format,
+// rebuilds them with NewRiddleFromConfig and expects the same riddle config again.
+func TestOutputFormatRoundTrip(t *testing.T) {
+	logrus.SetLevel(logrus.ErrorLevel)
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "concepts", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no sample concepts found: %v", err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var concept models.RiddleConcept
+		if err := json.Unmarshal(data, &concept); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		width, height := concept.GridSize()
+		converted := 0
+		for seed := int64(1); seed <= 20; seed++ {
+			rng := random.NewSeededRand(seed)
+			riddle, err := models.NewRiddle(context.Background(), rng, width, height, concept.SuperSolution, concept.WordPool)
+			if err != nil {
+				continue
+			}
+			riddle, err = riddle.FillWithWords(context.Background(), rng)
+			if err != nil {
+				continue
+			}
+			converted++
+
+			riddleConfig := TransformToOutputFormat(riddle, concept.ThemeDescription)
+			if len(riddleConfig.Letters) != height || len(riddleConfig.Letters[0]) != width {
+				t.Errorf("%s seed %d: letters are %dx%d, want %dx%d", path, seed, len(riddleConfig.Letters[0]), len(riddleConfig.Letters), width, height)
+			}
+			rebuilt := models.NewRiddleFromConfig(riddleConfig)
+			if len(rebuilt.Edges) != len(riddle.Edges) {
+				t.Errorf("%s seed %d: rebuilt riddle has %d edges, want %d", path, seed, len(rebuilt.Edges), len(riddle.Edges))
+			}
+			roundTripped := TransformToOutputFormat(rebuilt, concept.ThemeDescription)
+			if !reflect.DeepEqual(roundTripped, riddleConfig) {
+				t.Errorf("%s seed %d: riddle config changed in the round trip", path, seed)
+			}
+		}
+		if converted == 0 {
+			t.Errorf("%s: no riddle could be generated", path)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// heartbeatSlack is added to every expected heartbeat to cover Redis round trips and scheduling delays.
+const heartbeatSlack = 30 * time.Second
+
+// loopHealth tracks when each job consumer is expected to report back next,
+// so a consumer stuck in Redis or in a job that ignores its timeout can be detected.
+type loopHealth struct {
+	deadlines []atomic.Int64
+}
+
+func newLoopHealth(consumerCount int) *loopHealth {
+	health := &loopHealth{deadlines: make([]atomic.Int64, consumerCount)}
+	for consumer := range health.deadlines {
+		health.expect(consumer, 0)
+	}
+	return health
+}
+
+// expect records a heartbeat of the consumer and the time within which it has to beat again.
+func (health *loopHealth) expect(consumer int, within time.Duration) {
+	health.deadlines[consumer].Store(time.Now().Add(within + heartbeatSlack).UnixNano())
+}
+
+func (health *loopHealth) stalledConsumers() int {
+	now := time.Now().UnixNano()
+	stalled := 0
+	for i := range health.deadlines {
+		if health.deadlines[i].Load() < now {
+			stalled++
+		}
+	}
+	return stalled
+}
+
+// handleHealthz reports whether all job consumers are still making progress.
+func handleHealthz(health *loopHealth) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if stalled := health.stalledConsumers(); stalled > 0 {
+			logrus.Warnf("Health check failed: %d job consumers stalled", stalled)
+			http.Error(w, "job consumers stalled", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
+
+// handleReadyz reports whether the worker can take jobs: Redis is reachable and no shutdown is in progress.
+// The configuration is validated on startup, the worker does not run with an invalid one.
+func handleReadyz(shutdownCtx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if shutdownCtx.Err() != nil {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		pingCtx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := client.Ping(pingCtx).Err(); err != nil {
+			http.Error(w, "redis unreachable: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}
+}
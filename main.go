@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"os"
-	"strconv"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"straenge-riddle-worker/m/convert"
@@ -43,141 +46,199 @@ func init() {
 }
 
 func main() {
-	redisUrl, success := os.LookupEnv("REDIS_URL")
-	if !success {
-		logrus.Fatal("REDIS_URL not set")
-		return
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
 	}
+
+	cfg := loadConfig()
 	client = redis.NewClient(&redis.Options{
-		Addr: redisUrl,
+		Addr: cfg.RedisUrl,
 	})
 
-	parallelCountStr, success := os.LookupEnv("PARALLEL_COUNT")
-	if !success {
-		logrus.Fatal("PARALLEL_COUNT not set")
-		return
-	}
-	parallelCount, err := strconv.Atoi(parallelCountStr)
-	if err != nil {
-		logrus.Fatalf("Invalid PARALLEL_COUNT value: %v", err)
-		return
-	}
+	// stop taking new jobs on SIGTERM/SIGINT, the job in progress gets a grace period
+	shutdownCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
 
-	jobTimeoutStr, success := os.LookupEnv("JOB_TIMEOUT_SECONDS")
-	if !success {
-		logrus.Fatal("JOB_TIMEOUT_SECONDS not set")
-		return
-	}
-	jobTimeout, err := strconv.Atoi(jobTimeoutStr)
-	if err != nil {
-		logrus.Fatalf("Invalid JOB_TIMEOUT_SECONDS value: %v", err)
-		return
+	health := newLoopHealth(cfg.JobConcurrency)
+	if cfg.HTTPAddr != "" {
+		registerQueueMetrics()
+		startHTTPServer(shutdownCtx, cfg.HTTPAddr, health)
 	}
 
-	retryTimeoutStr, success := os.LookupEnv("RETRY_TIMEOUT_SECONDS")
-	if !success {
-		logrus.Fatal("RETRY_TIMEOUT_SECONDS not set")
-		return
-	}
-	retryTimeout, err := strconv.Atoi(retryTimeoutStr)
-	if err != nil {
-		logrus.Fatalf("Invalid RETRY_TIMEOUT_SECONDS value: %v", err)
-		return
+	go newReaper(cfg.ReaperInterval, cfg.Retry).run(shutdownCtx)
+	go runDelayedQueue(shutdownCtx, time.Second)
+
+	logrus.Infof("Started worker %s with %d concurrent jobs and %d concurrent attempts...", cfg.WorkerID, cfg.JobConcurrency, cfg.AttemptBudget)
+
+	budget := newAttemptBudget(cfg.AttemptBudget)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.JobConcurrency; i++ {
+		wg.Add(1)
+		go func(consumer int) {
+			defer wg.Done()
+			consumeJobs(shutdownCtx, cfg, budget, health, consumer)
+		}(i)
 	}
+	wg.Wait()
 
-	logrus.Info("Started worker...")
+	logrus.Info("Worker stopped")
+}
 
-	for {
-		time.Sleep(10 * time.Second)
-		logrus.Info("Waiting for jobs of source generate-riddle...")
-		jobRaw, err := client.RPopLPush(ctx, "generate-riddle", "processing").Result()
-		if err == redis.Nil {
+// consumeJobs takes jobs from the queue one after another until shutdown is requested.
+func consumeJobs(shutdownCtx context.Context, cfg *workerConfig, budget attemptBudget, health *loopHealth, consumer int) {
+	for shutdownCtx.Err() == nil {
+		health.expect(consumer, cfg.PollInterval)
+		logrus.Debugf("Waiting for jobs of source %s...", queueJobs)
+		// block until a job is available, the poll interval only matters while the queue is empty
+		jobRaw, err := client.BLMove(shutdownCtx, queueJobs, queueProcessing, "RIGHT", "LEFT", cfg.PollInterval).Result()
+		if err == redis.Nil || (err != nil && shutdownCtx.Err() != nil) {
 			continue
 		} else if err != nil {
 			logrus.Errorf("Redis Error: %v", err)
+			time.Sleep(cfg.PollInterval)
 			continue
 		}
-
-		var job models.Job
-		if err := json.Unmarshal([]byte(jobRaw), &job); err != nil {
-			logrus.Errorf("❌ Invalid Job: %v", err)
-			client.LRem(ctx, "processing", 1, jobRaw)
+		// a job that arrived while shutdown was requested is not started
+		if shutdownCtx.Err() != nil {
+			if err := handBackJob(jobRaw); err != nil {
+				logrus.Errorf("Redis Error while handing back job: %v", err)
+				continue
+			}
+			metricJobs.WithLabelValues(jobResultHandedBack).Inc()
+			logrus.Warnf("↩️ Job taken during shutdown handed back to queue %s", queueJobs)
 			continue
 		}
+		health.expect(consumer, cfg.Retry.MaxTimeout()+cfg.ShutdownGrace)
+		handleJob(shutdownCtx, cfg, budget, jobRaw)
+	}
+}
 
-		timeout := jobTimeout
-		if job.Type == "retry" {
-			timeout = retryTimeout
-		}
+// handleJob processes a single job taken from the queue.
+// If shutdownCtx is canceled while the job runs, the job may finish within the shutdown grace period,
+// otherwise it is aborted and handed back to the job queue for another worker.
+func handleJob(shutdownCtx context.Context, cfg *workerConfig, budget attemptBudget, jobRaw string) {
+	lease := acquireLease(ctx, jobRaw, cfg.WorkerID, cfg.LeaseTtl)
+	// the lease is held until the entry has left the processing list
+	defer lease.release(ctx)
+
+	var job models.Job
+	if err := json.Unmarshal([]byte(jobRaw), &job); err != nil {
+		logrus.Errorf("❌ Invalid Job: %v", err)
+		metricJobFailures.WithLabelValues(models.ErrInvalidJob).Inc()
+		metricJobs.WithLabelValues(jobResultFailed).Inc()
+		now := time.Now().UTC()
+		pushDeadLetter(ctx, jobRaw, models.JobFailure{
+			ErrType:       models.ErrInvalidJob,
+			Message:       err.Error(),
+			Attempts:      1,
+			StartedAt:     now,
+			FinishedAt:    now,
+			ParallelCount: cfg.ParallelCount,
+			Raw:           jobRaw,
+		})
+		return
+	}
 
-		startedAt := time.Now().UTC()
+	normalizeAttempt(&job)
+	timeout := cfg.Retry.TimeoutFor(job.Attempt)
 
-		logrus.Infof("Job type: %s, timeout: %d seconds", job.Type, timeout)
-		ctxTimeout, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	startedAt := time.Now().UTC()
 
-		riddle, err := processJob(ctxTimeout, job, parallelCount)
+	logrus.Infof("Job type: %s, attempt: %d/%d, timeout: %s", job.Type, job.Attempt+1, cfg.Retry.MaxAttempts, timeout)
+	ctxTimeout, cancel := context.WithTimeout(ctx, timeout)
 
-		cancel()
+	var interrupted atomic.Bool
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-shutdownCtx.Done():
+		}
+		logrus.Warnf("Shutdown requested, waiting up to %s for the current job", cfg.ShutdownGrace)
+		select {
+		case <-done:
+		case <-time.After(cfg.ShutdownGrace):
+			interrupted.Store(true)
+			cancel()
+		}
+	}()
+
+	result, err := processJob(ctxTimeout, job, generationOptions{
+		ParallelCount:      cfg.ParallelCount,
+		Budget:             budget,
+		Dictionary:         cfg.Dictionary,
+		Blocklist:          cfg.Blocklist,
+		DefaultWordLengths: cfg.WordLengths,
+	})
 
-		client.LRem(ctx, "processing", 1, jobRaw) // clean up processing list
+	close(done)
+	cancel()
 
-		if err != nil {
-			logrus.Errorf("❌ Job failed: %v", err)
-			if job.Type != "retry" {
-				job.Type = "retry"
-				jobRaw, err := json.Marshal(job)
-				if err != nil {
-					continue
-				}
-				client.LPush(ctx, "generate-riddle", jobRaw)
-			}
-			continue
+	if interrupted.Load() && err != nil {
+		// hand the job back unchanged, it did not fail
+		if handoffErr := handBackJob(jobRaw); handoffErr != nil {
+			logrus.Errorf("Redis Error while handing back job: %v", handoffErr)
+			return
 		}
+		metricJobs.WithLabelValues(jobResultHandedBack).Inc()
+		logrus.Warnf("↩️ Job interrupted by shutdown and handed back to queue %s", queueJobs)
+		return
+	}
 
-		var riddleConcept models.RiddleConcept
-		err = json.Unmarshal([]byte(job.Payload), &riddleConcept)
-		if err != nil {
-			logrus.Errorf("❌ Riddle concept could not be deserialized: %v", err)
-			continue
-		}
+	if err != nil {
+		logrus.Errorf("❌ Job failed: %v", err)
+		handleJobFailure(ctx, cfg, jobRaw, job, err, startedAt)
+		return
+	}
 
-		output := convert.TransformToOutputFormat(riddle, riddleConcept.ThemeDescription)
-
-		outputJson, err := json.Marshal(output)
-		if err != nil {
-			logrus.Errorf("❌ Output could not be serialized: %v", err)
-			if job.Type != "retry" {
-				job.Type = "retry"
-				jobRaw, err := json.Marshal(job)
-				if err != nil {
-					continue
-				}
-				client.LPush(ctx, "generate-riddle", jobRaw)
-			}
-			continue
-		}
+	var riddleConcept models.RiddleConcept
+	err = json.Unmarshal([]byte(job.Payload), &riddleConcept)
+	if err != nil {
+		logrus.Errorf("❌ Riddle concept could not be deserialized: %v", err)
+		handleJobFailure(ctx, cfg, jobRaw, job, &models.RiddleError{ErrType: models.ErrInvalidConcept, Message: err.Error()}, startedAt)
+		return
+	}
 
-		res := models.JobSuccess{
-			ParallelCount: parallelCount,
-			SuperSolution: riddleConcept.SuperSolution,
-			Output:        string(outputJson),
-			StartedAt:     startedAt,
-			FinishedAt:    time.Now().UTC(),
-		}
-		resJson, err := json.Marshal(res)
-		if err != nil {
-			logrus.Errorf("❌ Result could not be serialized: %v", err)
-			if job.Type != "retry" {
-				job.Type = "retry"
-				jobRaw, err := json.Marshal(job)
-				if err != nil {
-					continue
-				}
-				client.LPush(ctx, "generate-riddle", jobRaw)
-			}
-			continue
-		}
-		client.LPush(ctx, "generate-riddle-result", resJson)
-		logrus.Info("✅ Job successfully processed and result saved to queue generate-riddle-result")
+	output := convert.TransformToOutputFormat(result.Riddle, riddleConcept.ThemeDescription)
+
+	outputJson, err := json.Marshal(output)
+	if err != nil {
+		logrus.Errorf("❌ Output could not be serialized: %v", err)
+		handleJobFailure(ctx, cfg, jobRaw, job, err, startedAt)
+		return
+	}
+
+	res := models.JobSuccess{
+		ParallelCount: cfg.ParallelCount,
+		SuperSolution: riddleConcept.SuperSolution,
+		Output:        string(outputJson),
+		StartedAt:     startedAt,
+		FinishedAt:    time.Now().UTC(),
+		Seed:          result.Seed,
+		BonusWords:    result.BonusWords,
+		Lint:          result.Lint,
+	}
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		logrus.Errorf("❌ Result could not be serialized: %v", err)
+		handleJobFailure(ctx, cfg, jobRaw, job, err, startedAt)
+		return
 	}
+	published, err := finishJob(ctx, jobRaw, "LPUSH", queueResults, resJson)
+	if err != nil {
+		logrus.Errorf("Redis Error while saving result: %v", err)
+		return
+	}
+	if !published {
+		return
+	}
+	metricJobs.WithLabelValues(jobResultSuccess).Inc()
+	logrus.Infof("✅ Job successfully processed and result saved to queue %s", queueResults)
+}
+
+// handBackJob moves a job from the processing list back to the job queue unchanged, as the next job to be taken.
+func handBackJob(jobRaw string) error {
+	_, err := finishJob(ctx, jobRaw, "RPUSH", queueJobs, jobRaw) // RPUSH so it is the next job to be taken
+	return err
 }
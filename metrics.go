@@ -0,0 +1,81 @@
+package main
+
+import (
+	"straenge-riddle-worker/m/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	jobResultSuccess    = "success"
+	jobResultRetried    = "retried"
+	jobResultFailed     = "failed"
+	jobResultHandedBack = "handed_back"
+)
+
+var (
+	metricJobs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "riddle_worker_jobs_total",
+		Help: "Jobs handled by this worker, by result (success, retried, failed, handed_back).",
+	}, []string{"result"})
+	metricJobFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "riddle_worker_job_failures_total",
+		Help: "Failed job attempts, by error type.",
+	}, []string{"err_type"})
+	metricAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "riddle_worker_generation_attempts_total",
+		Help: "Single riddle generation attempts, by result (success or error type).",
+	}, []string{"result"})
+	metricAttemptsPerSuccess = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "riddle_worker_attempts_per_success",
+		Help:    "Generation attempts needed until the first valid riddle of a job was found.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+	metricTimeToRiddle = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "riddle_worker_time_to_riddle_seconds",
+		Help:    "Time until the first valid riddle of a job was found.",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+	})
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "riddle_worker_fill_backtracks_total",
+		Help: "Candidate cells discarded by the word fill search after trying them.",
+	}, func() float64 {
+		return float64(models.FillBacktracks.Load())
+	})
+	_ = promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "riddle_worker_super_solution_resamples_total",
+		Help: "Super solution placements discarded because the word pool could not fill the remaining islands.",
+	}, func() float64 {
+		return float64(models.SuperSolutionResamples.Load())
+	})
+)
+
+// registerQueueMetrics exposes the length of the worker's Redis queues, read on every scrape.
+func registerQueueMetrics() {
+	for _, queue := range []string{queueJobs, queueProcessing, queueResults, queueFailed} {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "riddle_worker_queue_length",
+			Help:        "Number of entries in a Redis queue.",
+			ConstLabels: prometheus.Labels{"queue": queue},
+		}, func() float64 {
+			return float64(client.LLen(ctx, queue).Val())
+		})
+	}
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name:        "riddle_worker_queue_length",
+		Help:        "Number of entries in a Redis queue.",
+		ConstLabels: prometheus.Labels{"queue": queueDelayed},
+	}, func() float64 {
+		return float64(client.ZCard(ctx, queueDelayed).Val())
+	})
+}
+
+func observeAttempt(err error) {
+	if err == nil {
+		metricAttempts.WithLabelValues(jobResultSuccess).Inc()
+		return
+	}
+	errType, _ := describeError(err)
+	metricAttempts.WithLabelValues(errType).Inc()
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// AlternativePlacement is a path of adjacent cells that spells a word of the riddle but is not where the word is placed.
+type AlternativePlacement struct {
+	Word  *RiddleWord
+	Nodes []*Node
+}
+
+func (placement AlternativePlacement) String() string {
+	var description strings.Builder
+	description.WriteString(placement.Word.Word + " can also be read at")
+	for _, node := range placement.Nodes {
+		fmt.Fprintf(&description, " %d,%d", node.Row, node.Col)
+	}
+	return description.String()
+}
+
+// CheckForAmbiguity reports whether any word of the riddle can be read somewhere else than at its placement,
+// together with all alternative placements found.
+func (riddle *Riddle) CheckForAmbiguity(ctx context.Context) (bool, []AlternativePlacement, error) {
+	alternatives, err := riddle.FindAlternativePlacements(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	return len(alternatives) > 0, alternatives, nil
+}
+
+// FindAlternativePlacements enumerates every simple path of adjacent cells that spells a word of the riddle,
+// placed or only in the pool, and returns the paths that are not the placement of the word.
+// Edges of other words do not block a path, the player can select any adjacent cells.
+// A path over exactly the cells of the placed word in another order selects the same cells, so it is no alternative.
+func (riddle *Riddle) FindAlternativePlacements(ctx context.Context) ([]AlternativePlacement, error) {
+	var alternatives []AlternativePlacement
+	visited := make([]bool, len(riddle.Nodes))
+	checkedWords := map[string]bool{}
+	for _, word := range riddle.Words {
+		if word.Length() == 0 || checkedWords[word.Word] {
+			continue
+		}
+		checkedWords[word.Word] = true
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
+		riddle.forEachWordPath(word, visited, func(path []*Node) bool {
+			if !isPlacementOf(word, path) {
+				alternatives = append(alternatives, AlternativePlacement{Word: word, Nodes: append([]*Node(nil), path...)})
+			}
+			return true
+		})
+	}
+	return alternatives, nil
+}
+
+// hasAlternativePlacement reports whether any word of the riddle can be read somewhere else than at its placement,
+// stopping at the first alternative. Empty cells spell nothing, so on a partly filled board only the filled cells are read.
+// Filling more cells only adds paths, so a board with an alternative stays ambiguous however it is completed.
+func (riddle *Riddle) hasAlternativePlacement() bool {
+	visited := make([]bool, len(riddle.Nodes))
+	checkedWords := map[string]bool{}
+	for _, word := range riddle.Words {
+		if word.Length() == 0 || checkedWords[word.Word] {
+			continue
+		}
+		checkedWords[word.Word] = true
+		completed := riddle.forEachWordPath(word, visited, func(path []*Node) bool {
+			return isPlacementOf(word, path)
+		})
+		if !completed {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachWordPath calls found with every simple path of adjacent cells that spells the word, until found returns false.
+// It reports whether all paths were passed to found. The path is only valid during the call.
+// visited must be all false and is all false again afterwards.
+func (riddle *Riddle) forEachWordPath(word *RiddleWord, visited []bool, found func(path []*Node) bool) bool {
+	path := make([]*Node, 0, word.Length())
+	for index, node := range riddle.Nodes {
+		if !node.hasLetter(word.RuneAt(0)) {
+			continue
+		}
+		visited[index] = true
+		completed := riddle.extendWordPath(word, append(path, node), visited, found)
+		visited[index] = false
+		if !completed {
+			return false
+		}
+	}
+	return true
+}
+
+func (riddle *Riddle) extendWordPath(word *RiddleWord, path []*Node, visited []bool, found func(path []*Node) bool) bool {
+	if len(path) == word.Length() {
+		return found(path)
+	}
+	last := path[len(path)-1]
+	nextLetter := word.RuneAt(len(path))
+	for _, index := range riddle.grid().neighbors[last.Row*riddle.Width+last.Col] {
+		next := riddle.Nodes[index]
+		if visited[index] || !next.hasLetter(nextLetter) {
+			continue
+		}
+		visited[index] = true
+		completed := riddle.extendWordPath(word, append(path, next), visited, found)
+		visited[index] = false
+		if !completed {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlacementOf reports whether the path covers exactly the cells of a placed word with the same spelling.
+func isPlacementOf(word *RiddleWord, path []*Node) bool {
+	placedWord := path[0].RiddleWord
+	if placedWord.Word != word.Word || placedWord.Length() != len(path) {
+		return false
+	}
+	for _, node := range path {
+		if node.RiddleWord != placedWord {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// riddleFromRows builds a riddle with one word per row, placed from left to right, and the given pool words.
+func riddleFromRows(rows []string, poolWords ...string) *Riddle {
+	riddleConfig := &RiddleConfig{}
+	for row, word := range rows {
+		solution := SolutionConfig{Word: word}
+		var letters []string
+		for col, letter := range []rune(word) {
+			letters = append(letters, string(letter))
+			solution.Locations = append(solution.Locations, LetterLocation{Row: row, Col: col})
+		}
+		riddleConfig.Letters = append(riddleConfig.Letters, letters)
+		riddleConfig.Solutions = append(riddleConfig.Solutions, solution)
+	}
+	riddle := NewRiddleFromConfig(riddleConfig)
+	for _, word := range poolWords {
+		riddle.Words = append(riddle.Words, &RiddleWord{Word: MakeWordSafe(word)})
+	}
+	return riddle
+}
+
+func describePlacements(alternatives []AlternativePlacement) []string {
+	var descriptions []string
+	for _, alternative := range alternatives {
+		descriptions = append(descriptions, alternative.String())
+	}
+	return descriptions
+}
+
+func TestFindAlternativePlacements(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      []string
+		poolWords []string
+		want      []string
+	}{
+		{
+			name: "unambiguous",
+			rows: []string{"HUND", "TIER"},
+		},
+		{
+			name: "placed word with another path",
+			rows: []string{"HUND", "OFEN"},
+			want: []string{"OFEN can also be read at 1,0 1,1 1,2 0,2"},
+		},
+		{
+			name:      "unused pool word on the board",
+			rows:      []string{"HUND", "TIER"},
+			poolWords: []string{"Tun"},
+			want:      []string{"TUN can also be read at 1,0 0,1 0,2"},
+		},
+		{
+			name:      "paths do not revisit cells",
+			rows:      []string{"ANNA", "BERG"},
+			poolWords: []string{"Nan"},
+		},
+		{
+			name: "every path of every word",
+			rows: []string{"EIS", "EIO"},
+			want: []string{
+				"EIS can also be read at 0,0 1,1 0,2",
+				"EIS can also be read at 1,0 1,1 0,2",
+				"EIS can also be read at 1,0 0,1 0,2",
+				"EIO can also be read at 0,0 0,1 1,2",
+				"EIO can also be read at 0,0 1,1 1,2",
+				"EIO can also be read at 1,0 0,1 1,2",
+			},
+		},
+		{
+			name: "same cells in another order",
+			rows: []string{"ANNA", "BERG"},
+		},
+	}
+	for _, test := range tests {
+		riddle := riddleFromRows(test.rows, test.poolWords...)
+		ambiguous, alternatives, err := riddle.CheckForAmbiguity(context.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := describePlacements(alternatives); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: alternatives = %q, want %q", test.name, got, test.want)
+		}
+		if ambiguous != (len(test.want) > 0) {
+			t.Errorf("%s: ambiguous = %t", test.name, ambiguous)
+		}
+	}
+}
+
+func TestFindAlternativePlacementsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := riddleFromRows([]string{"HUND", "OFEN"}).FindAlternativePlacements(ctx)
+	if !IsCanceled(err) {
+		t.Errorf("err = %v, want a canceled error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Dictionary is a set of words that can be searched for on a board, stored as a trie of their letters.
+type Dictionary struct {
+	root  *dictionaryNode
+	count int
+}
+
+type dictionaryNode struct {
+	children map[rune]*dictionaryNode
+	word     bool
+}
+
+// NewDictionary returns a dictionary of the given words, made safe like the words of a riddle.
+func NewDictionary(words []string) *Dictionary {
+	dictionary := &Dictionary{root: &dictionaryNode{}}
+	for _, word := range words {
+		dictionary.add(MakeWordSafe(word))
+	}
+	return dictionary
+}
+
+// LoadDictionary reads a plain word list with one word per line. Empty lines and lines starting with # are skipped.
+func LoadDictionary(path string) (*Dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewDictionary(words), nil
+}
+
+func (dictionary *Dictionary) add(word string) {
+	if word == "" {
+		return
+	}
+	node := dictionary.root
+	for _, letter := range word {
+		child, ok := node.children[letter]
+		if !ok {
+			if node.children == nil {
+				node.children = map[rune]*dictionaryNode{}
+			}
+			child = &dictionaryNode{}
+			node.children[letter] = child
+		}
+		node = child
+	}
+	if !node.word {
+		node.word = true
+		dictionary.count++
+	}
+}
+
+// Len returns the number of distinct words in the dictionary.
+func (dictionary *Dictionary) Len() int {
+	return dictionary.count
+}
+
+// FindDictionaryWords returns every word of the dictionary with at least minLength letters that can be traced
+// along a simple path of adjacent cells, in alphabetical order. Like a player, the path ignores the edges of the words.
+func (riddle *Riddle) FindDictionaryWords(ctx context.Context, dictionary *Dictionary, minLength int) ([]string, error) {
+	found := map[string]bool{}
+	visited := make([]bool, len(riddle.Nodes))
+	var letters []rune
+	var trace func(index int, node *dictionaryNode)
+	trace = func(index int, node *dictionaryNode) {
+		letters = append(letters, riddle.Nodes[index].RiddleWord.RuneAt(riddle.Nodes[index].RiddleWordIndex))
+		visited[index] = true
+		if node.word && len(letters) >= minLength {
+			found[string(letters)] = true
+		}
+		for _, neighborIndex := range riddle.grid().neighbors[index] {
+			neighbor := riddle.Nodes[neighborIndex]
+			if visited[neighborIndex] || neighbor.RiddleWord == nil || neighbor.RiddleWordIndex < 0 {
+				continue
+			}
+			if child, ok := node.children[neighbor.RiddleWord.RuneAt(neighbor.RiddleWordIndex)]; ok {
+				trace(neighborIndex, child)
+			}
+		}
+		visited[index] = false
+		letters = letters[:len(letters)-1]
+	}
+	for index, node := range riddle.Nodes {
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
+		if node.RiddleWord == nil || node.RiddleWordIndex < 0 {
+			continue
+		}
+		if child, ok := dictionary.root.children[node.RiddleWord.RuneAt(node.RiddleWordIndex)]; ok {
+			trace(index, child)
+		}
+	}
+	words := make([]string, 0, len(found))
+	for word := range found {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words, nil
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindDictionaryWords(t *testing.T) {
+	// H U N D
+	// T I E R
+	riddle := riddleFromRows([]string{"HUND", "TIER"})
+	dictionary := NewDictionary([]string{"Hund", "Tier", "Hier", "Rind", "Tun", "Dir", "Hut", "Nudel", "Heu", "Hunde"})
+
+	got, err := riddle.FindDictionaryWords(context.Background(), dictionary, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// DIR, RIND and HEU are not traceable, NUDEL has no L
+	want := []string{"HIER", "HUND", "HUNDE", "HUT", "TIER", "TUN"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDictionaryWords(3) = %q, want %q", got, want)
+	}
+
+	got, err = riddle.FindDictionaryWords(context.Background(), dictionary, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"HIER", "HUND", "HUNDE", "TIER"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindDictionaryWords(4) = %q, want %q", got, want)
+	}
+}
+
+func TestFindDictionaryWordsDoesNotRevisitCells(t *testing.T) {
+	riddle := riddleFromRows([]string{"ANNA", "BERG"})
+	got, err := riddle.FindDictionaryWords(context.Background(), NewDictionary([]string{"Nan", "Anna"}), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"ANNA"}) {
+		t.Errorf("FindDictionaryWords() = %q, want [ANNA]", got)
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# Tiere\nHund\n\n  Katze  \nhund\nStraße\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dictionary, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dictionary.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", dictionary.Len())
+	}
+	riddle := riddleFromRows([]string{"KATZEN", "STRAẞE"})
+	got, err := riddle.FindDictionaryWords(context.Background(), dictionary, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"KATZE", "STRAẞE"}) {
+		t.Errorf("FindDictionaryWords() = %q, want [KATZE STRAẞE]", got)
+	}
+
+	if _, err := LoadDictionary(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadDictionary() of a missing file did not fail")
+	}
+}
@@ -1,10 +1,25 @@
 package models
 
+import (
+	"context"
+	"errors"
+)
+
 // enum for riddle error types
 const (
-	ErrWordFill   = "WordFillError"
-	ErrWordLength = "WordLengthError"
-	ErrAmbiguity  = "AmbiguityError"
+	ErrWordFill    = "WordFillError"
+	ErrWordLength  = "WordLengthError"
+	ErrAmbiguity   = "AmbiguityError"
+	ErrBlockedWord = "BlockedWordError"     // the board contains a word of the blocklist
+	ErrExhausted   = "SearchExhaustedError" // the exact cover search tried every board of the concept
+	ErrCanceled    = "CanceledError"
+	ErrTimeout     = "TimeoutError"
+
+	ErrInvalidJob     = "InvalidJobError"
+	ErrInvalidConcept = "InvalidConceptError"
+	ErrConceptLint    = "ConceptLintError" // the concept has lint errors, see LintConcept
+	ErrOrphaned       = "OrphanedError"
+	ErrInternal       = "InternalError"
 )
 
 type RiddleError struct {
@@ -15,3 +30,16 @@ type RiddleError struct {
 func (e *RiddleError) Error() string {
 	return e.ErrType + ": " + e.Message
 }
+
+// IsCanceled reports whether err stems from an aborted generation context.
+func IsCanceled(err error) bool {
+	var riddleErr *RiddleError
+	return errors.As(err, &riddleErr) && riddleErr.ErrType == ErrCanceled
+}
+
+func checkCanceled(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &RiddleError{ErrType: ErrCanceled, Message: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,478 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"math/rand"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// exactCoverTurn is how many dead ends and boards the search of one super solution placement goes through
+	// before it is suspended and the next placement takes its turn
+	exactCoverTurn = 1000
+	// exactCoverPlacements is how many suspended placements take turns, a new one is started when one is exhausted
+	exactCoverPlacements = 64
+)
+
+// errSearchStopped ends the search of a placement that is no longer pulled.
+var errSearchStopped = errors.New("search stopped")
+
+// exactCoverSearch solves the board of one super solution placement as an exact cover problem with Algorithm X:
+// every empty cell is a primary column that must be covered exactly once,
+// every pool word and every 2x2 block (at most one diagonal) are secondary columns covered at most once.
+// Rows are the paths of a word, they are generated when a column is chosen,
+// because all paths of the words on a 6x8 grid would not fit into memory.
+// A partial board on which a word can be read elsewhere or a blocked word can be traced is a dead end,
+// filling more cells only adds paths, so none of its completions would be accepted.
+type exactCoverSearch struct {
+	riddle    *Riddle
+	rng       *rand.Rand
+	blocklist *Dictionary
+	// yield passes each complete board, or nil for a dead end, to the scheduler in NewRiddleExactCover
+	yield func(*Riddle) bool
+	// partial boards dropped because of a second reading or a blocked word
+	pruned int
+}
+
+// NewRiddleExactCover searches the boards of a concept systematically instead of restarting random attempts.
+// Every complete board is passed to accept, the search continues with the next board as long as accept returns an error.
+// The placements of the super solution are searched in turns of exactCoverTurn dead ends and boards,
+// so a placement whose boards all fail does not hold up the others. Suspended placements resume where they stopped,
+// no board is searched twice. The rng decides the order of placements and columns, the search is repeatable with the same seed.
+// pause, if not nil, is called after every turn, the search stops with its error.
+// An exhausted search fails with ErrExhausted: every word was tried in every place and reading direction,
+// no board exists on which the words cover the grid, or none was accepted.
+func NewRiddleExactCover(ctx context.Context, rng *rand.Rand, width, height int, superSolution string, words []string, blocklist *Dictionary, accept func(*Riddle) error, pause func() error) (*Riddle, error) {
+	riddle, err := newEmptyRiddle(width, height, superSolution, words)
+	if err != nil {
+		return nil, err
+	}
+	nextPlacement, stopPlacements := iter.Pull(superSolutionPaths(riddle, rand.New(rand.NewSource(rng.Int63()))))
+	defer stopPlacements()
+
+	type placementSearch struct {
+		search *exactCoverSearch
+		next   func() (*Riddle, bool)
+		stop   func()
+	}
+	var placements []*placementSearch
+	defer func() {
+		for _, placement := range placements {
+			placement.stop()
+		}
+	}()
+	boards, pruned := 0, 0
+	var lastRejection error
+	for {
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
+		if len(placements) < exactCoverPlacements {
+			if path, ok := nextPlacement(); ok {
+				search := &exactCoverSearch{riddle: riddle.Copy(), rng: rand.New(rand.NewSource(rng.Int63())), blocklist: blocklist}
+				next, stop := iter.Pull(search.boards(ctx, path))
+				placements = append(placements, &placementSearch{search: search, next: next, stop: stop})
+			}
+		}
+		if len(placements) == 0 {
+			break
+		}
+		placement := placements[0]
+		placements = placements[1:]
+		exhausted := false
+		for step := 0; step < exactCoverTurn; step++ {
+			board, ok := placement.next()
+			if !ok {
+				exhausted = true
+				break
+			}
+			if board == nil {
+				continue
+			}
+			boards++
+			err := accept(board)
+			if err == nil {
+				placement.stop()
+				return board, nil
+			}
+			if IsCanceled(err) {
+				placement.stop()
+				return nil, err
+			}
+			logrus.Debug("[exactCover] Board ", boards, " rejected: ", err)
+			lastRejection = err
+		}
+		pruned += placement.search.pruned
+		placement.search.pruned = 0
+		if pause != nil {
+			if err := pause(); err != nil {
+				placement.stop()
+				return nil, err
+			}
+		}
+		if !exhausted {
+			placements = append(placements, placement)
+			continue
+		}
+		placement.stop()
+		// the search of a placement also ends when ctx is canceled, that does not exhaust it
+		if err := checkCanceled(ctx); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case boards == 0 && pruned == 0:
+		return nil, &RiddleError{ErrType: ErrExhausted, Message: "The words cannot cover the grid"}
+	case boards == 0:
+		return nil, &RiddleError{ErrType: ErrExhausted, Message: fmt.Sprintf("All %d partial boards had a second reading or a blocked word", pruned)}
+	}
+	return nil, &RiddleError{ErrType: ErrExhausted, Message: fmt.Sprintf("All %d boards were rejected and %d partial boards had a second reading or a blocked word, last: %v", boards, pruned, lastRejection)}
+}
+
+// superSolutionPaths yields the paths of the super solution from an edge to the opposite edge of the empty riddle.
+func superSolutionPaths(riddle *Riddle, rng *rand.Rand) iter.Seq[[]int] {
+	return func(yield func([]int) bool) {
+		word := riddle.Words[0]
+		var starts []int
+		for index, node := range riddle.Nodes {
+			if node.Row == 0 || node.Row == riddle.Height-1 || node.Col == 0 || node.Col == riddle.Width-1 {
+				starts = append(starts, index)
+			}
+		}
+		rng.Shuffle(len(starts), func(i, j int) { starts[i], starts[j] = starts[j], starts[i] })
+		paths := newPathBuilder(riddle)
+		for _, start := range starts {
+			paths.push(-1, start)
+			first := riddle.Nodes[start]
+			err := paths.extend(rng, word.Length()-1, func(node int, remaining int) bool {
+				for _, cell := range paths.cells {
+					if reachesOppositeEdge(riddle, first, riddle.Nodes[cell], 0) {
+						return true
+					}
+				}
+				return reachesOppositeEdge(riddle, first, riddle.Nodes[node], remaining)
+			}, func(path []int) error {
+				if !spansOppositeEdges(nodeLocations(riddle, path), riddle.Width, riddle.Height) || yield(path) {
+					return nil
+				}
+				return errSearchStopped
+			})
+			paths.pop()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// boards yields every complete board with the super solution on path, and nil for every dead end on the way.
+func (search *exactCoverSearch) boards(ctx context.Context, path []int) iter.Seq[*Riddle] {
+	return func(yield func(*Riddle) bool) {
+		search.yield = yield
+		if err := search.place(ctx, search.riddle.Words[0], path); err != nil && !errors.Is(err, errSearchStopped) && !IsCanceled(err) {
+			logrus.Error("[exactCover] Search failed: ", err)
+		}
+	}
+}
+
+// place puts the word on the path and covers the next column, unless the partial board is a dead end:
+// a word can be read elsewhere, a blocked word can be traced or the remaining islands cannot be covered.
+func (search *exactCoverSearch) place(ctx context.Context, word *RiddleWord, path []int) error {
+	if err := checkCanceled(ctx); err != nil {
+		return err
+	}
+	riddle := search.riddle
+	start := riddle.mark()
+	var previousNode *Node
+	for index, cell := range path {
+		riddle.placeLetter(riddle.Nodes[cell], word, index, previousNode)
+		previousNode = riddle.Nodes[cell]
+	}
+	if !word.IsSuperSolution {
+		riddle.markWordUsed(word)
+	}
+	var err error
+	switch {
+	case search.readsWrong(ctx):
+		search.pruned++
+		err = search.deadEnd()
+	case !riddle.canCoverIslands(riddle.GetAllSubgraphs()):
+		err = search.deadEnd()
+	default:
+		err = search.coverCells(ctx)
+	}
+	riddle.undoTo(start)
+	return err
+}
+
+// readsWrong reports whether a word of the riddle can be read somewhere else than at its placement
+// or a word of the blocklist can be traced over the filled cells.
+func (search *exactCoverSearch) readsWrong(ctx context.Context) bool {
+	if search.riddle.hasAlternativePlacement() {
+		return true
+	}
+	if search.blocklist == nil {
+		return false
+	}
+	blockedWords, err := search.riddle.FindDictionaryWords(ctx, search.blocklist, 1)
+	return err == nil && len(blockedWords) > 0
+}
+
+// deadEnd hands control to the scheduler, which counts dead ends towards the turn of the placement.
+func (search *exactCoverSearch) deadEnd() error {
+	if !search.yield(nil) {
+		return errSearchStopped
+	}
+	return nil
+}
+
+// coverCells chooses the empty cell with the fewest free neighbors as next column
+// and tries every unused word in both reading directions on the paths through it.
+func (search *exactCoverSearch) coverCells(ctx context.Context) error {
+	riddle := search.riddle
+	cell := search.mostConstrainedCell()
+	if cell == -1 {
+		return search.found()
+	}
+	// the paths through the cell only depend on the length, they are enumerated once for all words of a length
+	wordsByLength := map[int][]*RiddleWord{}
+	var lengths []int
+	for _, index := range search.rng.Perm(len(riddle.Words)) {
+		word := riddle.Words[index]
+		if word.Used || word.Length() == 0 {
+			continue
+		}
+		if wordsByLength[word.Length()] == nil {
+			lengths = append(lengths, word.Length())
+		}
+		wordsByLength[word.Length()] = append(wordsByLength[word.Length()], word)
+	}
+	paths := newPathBuilder(riddle)
+	for _, length := range lengths {
+		err := paths.through(search.rng, cell, length, func(path []int) error {
+			// each word spells a different board on the path in each reading direction
+			readings := [][]int{path, reversePath(path)}
+			for _, word := range wordsByLength[length] {
+				directions := search.rng.Perm(len(readings))
+				if isPalindrome(word) {
+					directions = directions[:1]
+				}
+				for _, direction := range directions {
+					if err := search.place(ctx, word, readings[direction]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// found passes a copy of the complete board to the scheduler.
+func (search *exactCoverSearch) found() error {
+	if !search.yield(search.riddle.Copy()) {
+		return errSearchStopped
+	}
+	return nil
+}
+
+// mostConstrainedCell returns the empty cell with the fewest empty neighbors reachable without crossing an edge,
+// or -1 if all cells are covered.
+func (search *exactCoverSearch) mostConstrainedCell() int {
+	riddle := search.riddle
+	topology := riddle.grid()
+	best, bestCount := -1, 0
+	for index, node := range riddle.Nodes {
+		if !node.isEmpty() {
+			continue
+		}
+		count := 0
+		for _, neighborIndex := range topology.neighbors[index] {
+			neighbor := riddle.Nodes[neighborIndex]
+			if neighbor.isEmpty() && !riddle.crossesDiagonal(node, neighbor) {
+				count++
+			}
+		}
+		if best == -1 || count < bestCount {
+			best, bestCount = index, count
+		}
+	}
+	return best
+}
+
+// pathBuilder enumerates simple paths over empty cells that do not cross a drawn diagonal or themselves.
+// The diagonals of the path under construction are kept apart from the riddle, so the riddle can be changed
+// while a found path is being used.
+type pathBuilder struct {
+	riddle    *Riddle
+	cells     []int
+	inPath    []bool
+	diagonals []uint8
+	// blocks of the diagonals drawn by the path, -1 for straight steps
+	blocks []int
+}
+
+func newPathBuilder(riddle *Riddle) *pathBuilder {
+	riddle.grid()
+	return &pathBuilder{
+		riddle:    riddle,
+		inPath:    make([]bool, len(riddle.Nodes)),
+		diagonals: make([]uint8, len(riddle.diagonals)),
+	}
+}
+
+// canStep reports whether the path can go from cell from to the empty cell to.
+func (paths *pathBuilder) canStep(from, to int) bool {
+	riddle := paths.riddle
+	if paths.inPath[to] || !riddle.Nodes[to].isEmpty() {
+		return false
+	}
+	if from == -1 {
+		return true
+	}
+	block, kind := riddle.topology.diagonalOf(riddle.Nodes[from], riddle.Nodes[to])
+	if kind == noDiagonal {
+		return true
+	}
+	return (riddle.diagonals[block] == noDiagonal || riddle.diagonals[block] == kind) && paths.diagonals[block] == noDiagonal
+}
+
+// push appends the cell to the path, from is the cell it is connected to or -1.
+func (paths *pathBuilder) push(from, to int) {
+	block := -1
+	if from != -1 {
+		var kind uint8
+		block, kind = paths.riddle.topology.diagonalOf(paths.riddle.Nodes[from], paths.riddle.Nodes[to])
+		if kind == noDiagonal {
+			block = -1
+		} else {
+			paths.diagonals[block] = kind
+		}
+	}
+	paths.cells = append(paths.cells, to)
+	paths.blocks = append(paths.blocks, block)
+	paths.inPath[to] = true
+}
+
+func (paths *pathBuilder) pop() {
+	last := len(paths.cells) - 1
+	if block := paths.blocks[last]; block != -1 {
+		paths.diagonals[block] = noDiagonal
+	}
+	paths.inPath[paths.cells[last]] = false
+	paths.cells = paths.cells[:last]
+	paths.blocks = paths.blocks[:last]
+}
+
+// extend appends steps more cells to the end of the path and calls found for every complete path.
+// Cells for which keep returns false, given the steps left after them, are skipped.
+func (paths *pathBuilder) extend(rng *rand.Rand, steps int, keep func(cell int, remaining int) bool, found func([]int) error) error {
+	if steps == 0 {
+		return found(append([]int(nil), paths.cells...))
+	}
+	last := paths.cells[len(paths.cells)-1]
+	neighbors := paths.riddle.topology.neighbors[last]
+	for _, i := range rng.Perm(len(neighbors)) {
+		next := neighbors[i]
+		if !paths.canStep(last, next) || (keep != nil && !keep(next, steps-1)) {
+			continue
+		}
+		paths.push(last, next)
+		err := paths.extend(rng, steps-1, keep, found)
+		paths.pop()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// through calls found for every path of length cells that contains cell, once per set of steps.
+// A path is built as cell followed by its tail, then the head in front of cell is added.
+func (paths *pathBuilder) through(rng *rand.Rand, cell int, length int, found func([]int) error) error {
+	paths.push(-1, cell)
+	defer paths.pop()
+	for _, headLength := range rng.Perm(length) {
+		tailLength := length - 1 - headLength
+		// a path and its reverse are the same cover, only the one with the shorter head is used
+		if headLength > tailLength {
+			continue
+		}
+		err := paths.extend(rng, tailLength, nil, func(tail []int) error {
+			// the head starts at cell again, tail stays blocked in inPath
+			head := newPathBuilder(paths.riddle)
+			copy(head.inPath, paths.inPath)
+			copy(head.diagonals, paths.diagonals)
+			head.push(-1, cell)
+			head.inPath[cell] = true
+			return head.extend(rng, headLength, nil, func(headPath []int) error {
+				if headLength == tailLength && headLength > 0 && headPath[1] > tail[1] {
+					return nil
+				}
+				return found(append(reversePath(headPath), tail[1:]...))
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reachesOppositeEdge reports whether a super solution starting at first can still reach
+// an edge opposite to one of the edges of first from node in the remaining steps.
+func reachesOppositeEdge(riddle *Riddle, first, node *Node, remaining int) bool {
+	distance := -1
+	consider := func(d int) {
+		if distance == -1 || d < distance {
+			distance = d
+		}
+	}
+	if first.Row == 0 {
+		consider(riddle.Height - 1 - node.Row)
+	}
+	if first.Row == riddle.Height-1 {
+		consider(node.Row)
+	}
+	if first.Col == 0 {
+		consider(riddle.Width - 1 - node.Col)
+	}
+	if first.Col == riddle.Width-1 {
+		consider(node.Col)
+	}
+	return distance != -1 && distance <= remaining
+}
+
+func nodeLocations(riddle *Riddle, path []int) []LetterLocation {
+	locations := make([]LetterLocation, len(path))
+	for i, cell := range path {
+		locations[i] = LetterLocation{Row: riddle.Nodes[cell].Row, Col: riddle.Nodes[cell].Col}
+	}
+	return locations
+}
+
+func isPalindrome(word *RiddleWord) bool {
+	letters := word.Letters()
+	for i := 0; i < len(letters)/2; i++ {
+		if letters[i] != letters[len(letters)-1-i] {
+			return false
+		}
+	}
+	return true
+}
+
+func reversePath(path []int) []int {
+	reversed := make([]int, len(path))
+	for i, cell := range path {
+		reversed[len(path)-1-i] = cell
+	}
+	return reversed
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"straenge-riddle-worker/m/random"
+)
+
+func TestNewRiddleExactCover(t *testing.T) {
+	accepted := 0
+	riddle, err := NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 4, "Bauernhof", []string{"Kuh", "Hund"}, nil, func(*Riddle) error {
+		accepted++
+		return nil
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted != 1 {
+		t.Errorf("accept called %d times, want 1", accepted)
+	}
+	for _, node := range riddle.Nodes {
+		if node.isEmpty() {
+			t.Errorf("cell %d/%d is empty", node.Row, node.Col)
+		}
+	}
+	for _, word := range riddle.Words {
+		if !word.Used {
+			t.Errorf("word %s is not placed", word.Word)
+		}
+	}
+	if !spansOppositeEdges(wordLocations(riddle, riddle.Words[0]), riddle.Width, riddle.Height) {
+		t.Error("the super solution does not span opposite edges")
+	}
+
+	again, err := NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 4, "Bauernhof", []string{"Kuh", "Hund"}, nil, func(*Riddle) error { return nil }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(boardLetters(again), boardLetters(riddle)) {
+		t.Error("the same seed found a different board")
+	}
+}
+
+func TestNewRiddleExactCoverExhausted(t *testing.T) {
+	// KAFFEE leaves 10 cells, no subset of 4, 4, 4 and 7 letters adds up to 10
+	var riddleErr *RiddleError
+	_, err := NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 4, "Kaffee", []string{"Hund", "Maus", "Esel", "Elefant"}, nil, func(*Riddle) error { return nil }, nil)
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrExhausted || !strings.Contains(riddleErr.Message, "cannot cover") {
+		t.Errorf("err = %v, want a %s without boards", err, ErrExhausted)
+	}
+
+	boards := 0
+	rejection := &RiddleError{ErrType: ErrAmbiguity, Message: "rejected by the test"}
+	// every board of BAUERNHOF and KUH on a 4x3 grid is rejected
+	_, err = NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 3, "Bauernhof", []string{"Kuh"}, nil, func(*Riddle) error {
+		boards++
+		return rejection
+	}, nil)
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrExhausted || !strings.Contains(riddleErr.Message, rejection.Error()) {
+		t.Errorf("err = %v, want a %s after rejecting every board", err, ErrExhausted)
+	}
+	if boards == 0 {
+		t.Error("no board was passed to accept")
+	}
+}
+
+func TestNewRiddleExactCoverPrunes(t *testing.T) {
+	boards := 0
+	_, err := NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 3, "Bauernhof", []string{"Kuh"}, nil, func(board *Riddle) error {
+		boards++
+		if ambiguous, alternatives, err := board.CheckForAmbiguity(context.Background()); err != nil || ambiguous {
+			t.Errorf("ambiguous board passed to accept: %v %v", alternatives, err)
+		}
+		return &RiddleError{ErrType: ErrAmbiguity, Message: "rejected by the test"}
+	}, nil)
+	if err == nil || boards == 0 {
+		t.Fatalf("err = %v after %d boards, want a rejection of every board", err, boards)
+	}
+
+	// every board spells the E of BAUERNHOF, so every partial board is dropped before it is complete
+	var riddleErr *RiddleError
+	_, err = NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 3, "Bauernhof", []string{"Kuh"}, NewDictionary([]string{"E"}), func(*Riddle) error {
+		t.Error("board with a blocked word passed to accept")
+		return nil
+	}, nil)
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrExhausted || !strings.Contains(riddleErr.Message, "blocked word") {
+		t.Errorf("err = %v, want a %s after dropping every partial board", err, ErrExhausted)
+	}
+}
+
+func TestNewRiddleExactCoverCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewRiddleExactCover(ctx, random.NewSeededRand(1), 6, 8, "Bauernhof", []string{"Kuh", "Hund", "Schwein", "Traktor"}, nil, func(*Riddle) error { return nil }, nil)
+	if !IsCanceled(err) {
+		t.Errorf("err = %v, want a %s", err, ErrCanceled)
+	}
+}
+
+func wordLocations(riddle *Riddle, word *RiddleWord) []LetterLocation {
+	locations := make([]LetterLocation, word.Length())
+	for _, node := range riddle.Nodes {
+		if node.RiddleWord == word {
+			locations[node.RiddleWordIndex] = LetterLocation{Row: node.Row, Col: node.Col}
+		}
+	}
+	return locations
+}
+
+func boardLetters(riddle *Riddle) []rune {
+	letters := make([]rune, len(riddle.Nodes))
+	for index, node := range riddle.Nodes {
+		letters[index] = node.RiddleWord.RuneAt(node.RiddleWordIndex)
+	}
+	return letters
+}
+
+func TestNewRiddleExactCoverSearchesEveryBoardOnce(t *testing.T) {
+	// boards by the word and letter index of every cell, different placements may spell the same letters.
+	// BUS and TOR share no letter with each other or KAFFEE, so no variant is dropped for a second reading.
+	boards := map[string]bool{}
+	var first []string
+	_, err := NewRiddleExactCover(context.Background(), random.NewSeededRand(1), 4, 3, "Kaffee", []string{"Bus", "Tor"}, nil, func(board *Riddle) error {
+		placement := boardPlacement(board)
+		key := strings.Join(placement, " ")
+		if boards[key] {
+			t.Errorf("board %v was passed to accept twice", placement)
+		}
+		boards[key] = true
+		if first == nil {
+			first = placement
+		}
+		return &RiddleError{ErrType: ErrAmbiguity, Message: "rejected by the test"}
+	}, nil)
+	var riddleErr *RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrExhausted || first == nil {
+		t.Fatalf("err = %v, want a %s after rejecting every board", err, ErrExhausted)
+	}
+
+	swapped := append([]string(nil), first...)
+	reversed := append([]string(nil), first...)
+	for cell, letter := range first {
+		switch word, index, _ := strings.Cut(letter, ":"); word {
+		case "BUS":
+			swapped[cell] = "TOR:" + index
+			reversed[cell] = "BUS:" + strconv.Itoa(2-int(index[0]-'0'))
+		case "TOR":
+			swapped[cell] = "BUS:" + index
+		}
+	}
+	if !boards[strings.Join(swapped, " ")] {
+		t.Errorf("board %v with BUS and TOR swapped was not searched", swapped)
+	}
+	if !boards[strings.Join(reversed, " ")] {
+		t.Errorf("board %v with BUS reversed was not searched", reversed)
+	}
+}
+
+func boardPlacement(riddle *Riddle) []string {
+	placement := make([]string, len(riddle.Nodes))
+	for index, node := range riddle.Nodes {
+		placement[index] = node.RiddleWord.Word + ":" + strconv.Itoa(node.RiddleWordIndex)
+	}
+	return placement
+}
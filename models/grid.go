@@ -0,0 +1,155 @@
+package models
+
+import "sync"
+
+// diagonal kinds of a 2x2 block of the grid, at most one of them can be drawn
+const (
+	noDiagonal   uint8 = 0
+	mainDiagonal uint8 = 1 // top left to bottom right
+	antiDiagonal uint8 = 2 // bottom left to top right
+)
+
+// gridTopology is the precomputed adjacency of all cells of a grid size.
+// Cells are addressed by their index row*width+col, like Riddle.Nodes.
+type gridTopology struct {
+	width  int
+	height int
+	// neighbors of every cell, in the order of GetAdjacentNodes
+	neighbors [][]int
+}
+
+type gridSize struct {
+	width  int
+	height int
+}
+
+var (
+	topologiesMutex sync.Mutex
+	topologies      = map[gridSize]*gridTopology{}
+)
+
+// neighborOffsets lists the eight neighbors of a cell as row and column offsets
+var neighborOffsets = [8][2]int{
+	{-1, 0}, {1, 0}, {0, -1}, {0, 1},
+	{-1, -1}, {-1, 1}, {1, -1}, {1, 1},
+}
+
+// topologyFor returns the shared topology of a grid size, computing it on first use.
+func topologyFor(width, height int) *gridTopology {
+	topologiesMutex.Lock()
+	defer topologiesMutex.Unlock()
+	size := gridSize{width: width, height: height}
+	if topology, ok := topologies[size]; ok {
+		return topology
+	}
+	topology := &gridTopology{
+		width:     width,
+		height:    height,
+		neighbors: make([][]int, width*height),
+	}
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			var neighbors []int
+			for _, offset := range neighborOffsets {
+				r, c := row+offset[0], col+offset[1]
+				if r >= 0 && r < height && c >= 0 && c < width {
+					neighbors = append(neighbors, r*width+c)
+				}
+			}
+			topology.neighbors[row*width+col] = neighbors
+		}
+	}
+	topologies[size] = topology
+	return topology
+}
+
+// diagonalOf returns the 2x2 block and the kind of the diagonal between two adjacent cells.
+// The kind is noDiagonal for horizontal and vertical neighbors and for cells that are not adjacent.
+func (topology *gridTopology) diagonalOf(node1, node2 *Node) (block int, kind uint8) {
+	dRow, dCol := node2.Row-node1.Row, node2.Col-node1.Col
+	if (dRow != 1 && dRow != -1) || (dCol != 1 && dCol != -1) {
+		return 0, noDiagonal
+	}
+	block = min(node1.Row, node2.Row)*(topology.width-1) + min(node1.Col, node2.Col)
+	if dRow == dCol {
+		return block, mainDiagonal
+	}
+	return block, antiDiagonal
+}
+
+// grid returns the topology of the riddle and builds the blocked diagonals from its edges on first use.
+// Edges added afterwards have to go through addEdge, so the blocked diagonals stay in sync.
+func (riddle *Riddle) grid() *gridTopology {
+	if riddle.topology == nil {
+		riddle.topology = topologyFor(riddle.Width, riddle.Height)
+		riddle.diagonals = make([]uint8, max(riddle.Width-1, 0)*max(riddle.Height-1, 0))
+		for _, edge := range riddle.Edges {
+			riddle.setDiagonal(edge, true)
+		}
+	}
+	return riddle.topology
+}
+
+func (riddle *Riddle) addEdge(edge *LetterEdge) {
+	riddle.grid()
+	riddle.Edges = append(riddle.Edges, edge)
+	riddle.setDiagonal(edge, true)
+}
+
+func (riddle *Riddle) setDiagonal(edge *LetterEdge, drawn bool) {
+	block, kind := riddle.topology.diagonalOf(edge.Node1, edge.Node2)
+	if kind == noDiagonal {
+		return
+	}
+	if drawn {
+		riddle.diagonals[block] = kind
+	} else {
+		riddle.diagonals[block] = noDiagonal
+	}
+}
+
+// crossesDiagonal reports whether an edge between two adjacent nodes would cross a drawn diagonal.
+func (riddle *Riddle) crossesDiagonal(node1, node2 *Node) bool {
+	block, kind := riddle.grid().diagonalOf(node1, node2)
+	if kind == noDiagonal {
+		return false
+	}
+	drawn := riddle.diagonals[block]
+	return drawn != noDiagonal && drawn != kind
+}
+
+// islandSizeAtLeast reports whether the empty island around start has at least limit nodes.
+// The search stops as soon as the limit is reached.
+func (riddle *Riddle) islandSizeAtLeast(start *Node, limit int) bool {
+	if limit <= 1 {
+		return true
+	}
+	topology := riddle.grid()
+	startIndex := start.Row*riddle.Width + start.Col
+	// islands that are too small have only a few nodes, so a linear scan of the visited nodes is enough
+	var buffer [16]int
+	visited := append(buffer[:0], startIndex)
+	for next := 0; next < len(visited); next++ {
+		current := riddle.Nodes[visited[next]]
+		for _, neighborIndex := range topology.neighbors[visited[next]] {
+			neighbor := riddle.Nodes[neighborIndex]
+			if !neighbor.isEmpty() || containsIndex(visited, neighborIndex) || riddle.crossesDiagonal(current, neighbor) {
+				continue
+			}
+			visited = append(visited, neighborIndex)
+			if len(visited) >= limit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsIndex(indices []int, index int) bool {
+	for _, i := range indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
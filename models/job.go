@@ -5,6 +5,11 @@ import "time"
 type Job struct {
 	Type    string `json:"Type"`
 	Payload string `json:"Payload"`
+	Attempt int    `json:"Attempt,omitempty"`
+	// optional seed to reproduce a riddle, see JobSuccess.Seed
+	Seed *int64 `json:"Seed,omitempty"`
+	// optional generation engine, "backtracking" (default) or "exact-cover"
+	Engine string `json:"Engine,omitempty"`
 }
 
 type JobSuccess struct {
@@ -13,4 +18,23 @@ type JobSuccess struct {
 	StartedAt     time.Time `json:"StartedAt"`
 	FinishedAt    time.Time `json:"FinishedAt"`
 	ParallelCount int       `json:"ParallelCount"`
+	// seed of the attempt that generated the riddle, a job with this seed regenerates it on its first attempt
+	Seed int64 `json:"Seed"`
+	// dictionary words that can be traced on the board but are not words of the concept
+	BonusWords []string `json:"BonusWords,omitempty"`
+	// lint warnings of the concept
+	Lint []LintFinding `json:"Lint,omitempty"`
+}
+
+type JobFailure struct {
+	Job           Job       `json:"Job"`
+	ErrType       string    `json:"ErrType"`
+	Message       string    `json:"Message"`
+	Attempts      int       `json:"Attempts"`
+	StartedAt     time.Time `json:"StartedAt"`
+	FinishedAt    time.Time `json:"FinishedAt"`
+	ParallelCount int       `json:"ParallelCount"`
+	Raw           string    `json:"Raw,omitempty"`
+	// lint report of the concept, if it failed with lint errors
+	Lint []LintFinding `json:"Lint,omitempty"`
 }
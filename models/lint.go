@@ -0,0 +1,197 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// severities of lint findings, errors make the generation of a concept impossible
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+)
+
+// MinSuperSolutionLength is the minimum number of letters of a super solution.
+const MinSuperSolutionLength = 6
+
+// letters that can appear on a board, besides A to Z
+const umlauts = "ÄÖÜẞ"
+
+// LintFinding is a problem of a riddle concept found before the generation.
+type LintFinding struct {
+	Severity string `json:"severity"`
+	Word     string `json:"word,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (finding LintFinding) String() string {
+	return finding.Severity + ": " + finding.Message
+}
+
+// ConceptLintError is returned for a concept with lint errors, it carries the complete lint report.
+// It unwraps to a RiddleError of type ErrConceptLint.
+type ConceptLintError struct {
+	Findings []LintFinding
+}
+
+func (e *ConceptLintError) Error() string {
+	return e.riddleError().Error()
+}
+
+func (e *ConceptLintError) Unwrap() error {
+	return e.riddleError()
+}
+
+func (e *ConceptLintError) riddleError() *RiddleError {
+	var messages []string
+	for _, finding := range e.Findings {
+		if finding.Severity == LintSeverityError {
+			messages = append(messages, finding.Message)
+		}
+	}
+	return &RiddleError{ErrType: ErrConceptLint, Message: strings.Join(messages, "; ")}
+}
+
+// HasLintErrors reports whether any of the findings is an error.
+func HasLintErrors(findings []LintFinding) bool {
+	for _, finding := range findings {
+		if finding.Severity == LintSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LintConcept checks a riddle concept for problems that make the generation impossible (errors)
+// or that rule out some of its words (warnings). Words are compared as they appear on the board, see MakeWordSafe.
+// Word lengths not set by the concept are taken from defaults, see RiddleConcept.WordLengths.
+func LintConcept(concept RiddleConcept, defaults WordLengths) []LintFinding {
+	var findings []LintFinding
+	report := func(severity, word, format string, args ...any) {
+		findings = append(findings, LintFinding{Severity: severity, Word: word, Message: fmt.Sprintf(format, args...)})
+	}
+	width, height := concept.GridSize()
+	cells := width * height
+	lengths := concept.WordLengths(defaults)
+	if !lengths.Valid() {
+		report(LintSeverityError, "", "word lengths of %s are not possible", lengths)
+	}
+
+	superSolution := MakeWordSafe(concept.SuperSolution)
+	superLength := utf8.RuneCountInString(superSolution)
+	if superLength < MinSuperSolutionLength {
+		report(LintSeverityError, superSolution, "super solution %s has %d letters, at least %d are required", superSolution, superLength, MinSuperSolutionLength)
+	}
+	if superLength < min(width, height) {
+		report(LintSeverityError, superSolution, "super solution %s has %d letters, too few to connect opposite edges of the %dx%d grid", superSolution, superLength, width, height)
+	}
+	if superLength > cells {
+		report(LintSeverityError, superSolution, "super solution %s has %d letters, more than the %d cells of the grid", superSolution, superLength, cells)
+	}
+	if invalid := invalidLetters(superSolution); invalid != "" {
+		report(LintSeverityError, superSolution, "super solution %s contains letters that cannot be shown on the board: %s", superSolution, invalid)
+	}
+	freeCells := cells - superLength
+	if freeCells > 0 && freeCells < lengths.Min {
+		report(LintSeverityError, superSolution, "super solution %s leaves %d cells, too few for a word of %d letters", superSolution, freeCells, lengths.Min)
+	}
+
+	seen := map[string]bool{}
+	var words []string
+	for _, original := range concept.WordPool {
+		word := MakeWordSafe(original)
+		if word == "" {
+			report(LintSeverityError, original, "word %q has no letters", original)
+			continue
+		}
+		if invalid := invalidLetters(word); invalid != "" {
+			report(LintSeverityError, word, "word %s contains letters that cannot be shown on the board: %s", word, invalid)
+			continue
+		}
+		if word == superSolution {
+			report(LintSeverityWarning, word, "word %s is the super solution", word)
+			continue
+		}
+		if seen[word] {
+			report(LintSeverityWarning, word, "word %s appears more than once", word)
+			continue
+		}
+		// words outside the length limits are left out of the word pool by the generation
+		if length := utf8.RuneCountInString(word); !lengths.Allows(length) {
+			report(LintSeverityWarning, word, "word %s has %d letters, only words with %s are used", word, length, lengths)
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+
+	placeableLetters := 0
+	var placeableLengths []int
+	for _, word := range words {
+		length := utf8.RuneCountInString(word)
+		// unused pool words are searched on the board as well, so a word inside the super solution can always be read there
+		if relation := containedIn(word, superSolution); relation != "" {
+			report(LintSeverityError, word, "word %s %s the super solution %s and could always be read there", word, relation, superSolution)
+			continue
+		}
+		placeable := true
+		if freeCells >= 0 && length > freeCells {
+			report(LintSeverityWarning, word, "word %s has %d letters, more than the %d cells left by the super solution", word, length, freeCells)
+			placeable = false
+		}
+		for _, other := range words {
+			if other == word {
+				continue
+			}
+			if relation := containedIn(other, word); relation != "" {
+				report(LintSeverityWarning, word, "word %s %s %s, so %s can never be placed without a second reading", other, relation, word, word)
+				placeable = false
+				break
+			}
+		}
+		if placeable {
+			placeableLetters += length
+			placeableLengths = append(placeableLengths, length)
+		}
+	}
+	switch {
+	case placeableLetters < freeCells:
+		report(LintSeverityError, "", "the placeable words have %d letters in total, not enough for the %d cells left by the super solution", placeableLetters, freeCells)
+	case freeCells > 0 && !planLengths(placeableLengths, freeCells).covers(freeCells):
+		report(LintSeverityError, "", "no combination of the placeable words has exactly the %d letters needed for the cells left by the super solution", freeCells)
+	}
+	return findings
+}
+
+// containedIn describes how word can be read inside other: as prefix, inside or backwards, or "" if it cannot.
+func containedIn(word, other string) string {
+	switch {
+	case strings.HasPrefix(other, word):
+		return "is the prefix of"
+	case strings.Contains(other, word):
+		return "is contained in"
+	case strings.Contains(other, reverse(word)):
+		return "is contained backwards in"
+	}
+	return ""
+}
+
+func reverse(word string) string {
+	letters := []rune(word)
+	for i, j := 0, len(letters)-1; i < j; i, j = i+1, j-1 {
+		letters[i], letters[j] = letters[j], letters[i]
+	}
+	return string(letters)
+}
+
+// invalidLetters returns the letters of a safe word that cannot be shown on the board.
+func invalidLetters(word string) string {
+	var invalid []rune
+	for _, letter := range word {
+		if (letter < 'A' || letter > 'Z') && !strings.ContainsRune(umlauts, letter) && !strings.ContainsRune(string(invalid), letter) {
+			invalid = append(invalid, letter)
+		}
+	}
+	return string(invalid)
+}
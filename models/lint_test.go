@@ -0,0 +1,137 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLintConcept(t *testing.T) {
+	farm := RiddleConcept{
+		SuperSolution: "Bauernhof",
+		WordPool:      []string{"Schwein", "Pferd", "Ziege", "Huhn", "Ente", "Schaf", "Hahn", "Katze", "Hund", "Esel", "Gans", "Traktor", "Scheune"},
+	}
+	tests := []struct {
+		name     string
+		concept  RiddleConcept
+		defaults WordLengths
+		// expected findings as "severity: part of the message"
+		want []string
+	}{
+		{
+			name:    "clean",
+			concept: farm,
+		},
+		{
+			name:    "super solution counted in letters, not bytes",
+			concept: RiddleConcept{SuperSolution: "Käse", WordPool: farm.WordPool, Width: 4, Height: 6},
+			want:    []string{"error: super solution KÄSE has 4 letters"},
+		},
+		{
+			name:    "super solution too short for the grid",
+			concept: RiddleConcept{SuperSolution: "Kaffee", WordPool: farm.WordPool, Width: 8, Height: 8},
+			want:    []string{"error: super solution KAFFEE has 6 letters, too few to connect opposite edges"},
+		},
+		{
+			name:    "super solution too long for the grid",
+			concept: RiddleConcept{SuperSolution: "Donaudampfschifffahrt", WordPool: farm.WordPool, Width: 4, Height: 4},
+			want:    []string{"error: super solution DONAUDAMPFSCHIFFFAHRT has 21 letters, more than the 16 cells"},
+		},
+		{
+			name:    "letters outside the alphabet",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Kuh's", "Ziege2"}, farm.WordPool...)},
+			want: []string{
+				"error: word KUH'S contains letters that cannot be shown on the board: '",
+				"error: word ZIEGE2 contains letters that cannot be shown on the board: 2",
+			},
+		},
+		{
+			name:    "duplicates after making the words safe",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"schwein", "Bauern-Hof"}, farm.WordPool...)},
+			want: []string{
+				"warning: word BAUERNHOF is the super solution",
+				"warning: word SCHWEIN appears more than once",
+			},
+		},
+		{
+			name:    "word inside the super solution",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Bauer", "Nreu"}, farm.WordPool...)},
+			want: []string{
+				"error: word BAUER is the prefix of the super solution BAUERNHOF",
+				"error: word NREU is contained backwards in the super solution BAUERNHOF",
+			},
+		},
+		{
+			name:    "word inside another word",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Hundehuette"}, farm.WordPool...)},
+			want:    []string{"warning: word HUND is the prefix of HUNDEHUETTE, so HUNDEHUETTE can never be placed"},
+		},
+		{
+			name:    "words too short or too long",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Kuh", "Futtertrogfuellmaschinenbetrieb"}, farm.WordPool...), Width: 5, Height: 7},
+			want: []string{
+				"warning: word KUH has 3 letters, only words with at least 4 letters are used",
+				"warning: word FUTTERTROGFUELLMASCHINENBETRIEB has 31 letters, more than the 26 cells",
+			},
+		},
+		{
+			name:    "concept allows shorter words",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Kuh"}, farm.WordPool...), MinWordLength: 3},
+		},
+		{
+			name:     "worker allows shorter words",
+			concept:  RiddleConcept{SuperSolution: "Bauernhof", WordPool: append([]string{"Kuh"}, farm.WordPool...)},
+			defaults: WordLengths{Min: 3},
+		},
+		{
+			name:    "words longer than the maximum",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: farm.WordPool, MaxWordLength: 5},
+			want: []string{
+				"warning: word SCHWEIN has 7 letters, only words with 4 to 5 letters are used",
+				"warning: word TRAKTOR has 7 letters",
+				"warning: word SCHEUNE has 7 letters",
+			},
+		},
+		{
+			name:    "not enough letters for the grid",
+			concept: RiddleConcept{SuperSolution: "Bauernhof", WordPool: []string{"Schwein", "Pferd"}},
+			want:    []string{"error: the placeable words have 12 letters in total, not enough for the 39 cells"},
+		},
+		{
+			name:    "no combination of word lengths fits the grid",
+			concept: RiddleConcept{SuperSolution: "Kaffee", WordPool: []string{"Hund", "Maus", "Esel", "Elefant"}, Width: 4, Height: 4},
+			want:    []string{"error: no combination of the placeable words has exactly the 10 letters"},
+		},
+	}
+	for _, test := range tests {
+		findings := LintConcept(test.concept, test.defaults)
+		if len(findings) != len(test.want) {
+			t.Errorf("%s: LintConcept() = %q, want %d findings", test.name, findings, len(test.want))
+			continue
+		}
+		for i, want := range test.want {
+			severity, message, _ := strings.Cut(want, ": ")
+			if findings[i].Severity != severity || !strings.Contains(findings[i].Message, message) {
+				t.Errorf("%s: finding %d = %q, want %q", test.name, i, findings[i], want)
+			}
+		}
+	}
+}
+
+func TestConceptLintError(t *testing.T) {
+	findings := LintConcept(RiddleConcept{SuperSolution: "Hof", WordPool: []string{"Kuh"}}, WordLengths{})
+	if !HasLintErrors(findings) {
+		t.Fatalf("LintConcept() = %q, want errors", findings)
+	}
+	var err error = &ConceptLintError{Findings: findings}
+	var riddleErr *RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrConceptLint {
+		t.Fatalf("ConceptLintError does not unwrap to a RiddleError of type %s", ErrConceptLint)
+	}
+	if strings.Contains(riddleErr.Message, "KUH") {
+		t.Errorf("message %q contains warnings", riddleErr.Message)
+	}
+	if !strings.Contains(riddleErr.Message, "super solution HOF has 3 letters") {
+		t.Errorf("message %q does not contain the errors", riddleErr.Message)
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "sync/atomic"
+
+// FillBacktracks counts the candidate nodes fillWordRecursive discarded after trying them,
+// summed over all riddles generated by this process.
+var FillBacktracks atomic.Int64
+
+// SuperSolutionResamples counts the super solution placements NewRiddle discarded
+// because the word pool could not fill the remaining subgraphs.
+var SuperSolutionResamples atomic.Int64
@@ -19,3 +19,7 @@ func ContainsNodePosition(nodes []*Node, node *Node) bool {
 	}
 	return false
 }
+
+func (node *Node) hasLetter(letter rune) bool {
+	return node.RiddleWord != nil && node.RiddleWordIndex >= 0 && node.RiddleWord.RuneAt(node.RiddleWordIndex) == letter
+}
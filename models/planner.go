@@ -0,0 +1,158 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// lengthPlan tells for every number of cells up to its size whether a subset of the planned words
+// has exactly that many letters in total, every word used at most once.
+// It is a necessary condition for filling cells with words: the search only tries words
+// that leave a number of cells the remaining words can still add up to.
+type lengthPlan []bool
+
+// planLengths computes the lengthPlan of the word lengths for up to cells cells.
+func planLengths(lengths []int, cells int) lengthPlan {
+	plan := make(lengthPlan, cells+1)
+	plan[0] = true
+	for _, length := range lengths {
+		// downwards, so every word is added at most once
+		for sum := cells; sum >= length && length > 0; sum-- {
+			if plan[sum-length] {
+				plan[sum] = true
+			}
+		}
+	}
+	return plan
+}
+
+// covers reports whether some of the words have exactly cells letters in total.
+func (plan lengthPlan) covers(cells int) bool {
+	return cells >= 0 && cells < len(plan) && plan[cells]
+}
+
+// planUnusedWords computes the lengthPlan of the unused pool words, leaving out the given word.
+func (riddle *Riddle) planUnusedWords(cells int, except *RiddleWord) lengthPlan {
+	var lengths []int
+	for _, word := range riddle.Words {
+		if !word.Used && word != except {
+			lengths = append(lengths, word.Length())
+		}
+	}
+	return planLengths(lengths, cells)
+}
+
+// planIslands reports whether all islands can be covered at the same time,
+// every island by its own subset of the word lengths adding up exactly to its size, no word used twice.
+func planIslands(sizes []int, lengths []int) bool {
+	total, letters, largest := 0, 0, 0
+	for _, size := range sizes {
+		total += size
+		largest = max(largest, size)
+	}
+	for _, length := range lengths {
+		letters += length
+	}
+	if total > letters {
+		return false
+	}
+	// every island on its own is a cheap necessary condition
+	plan := planLengths(lengths, largest)
+	for _, size := range sizes {
+		if !plan.covers(size) {
+			return false
+		}
+	}
+	planner := &islandPlanner{
+		sizes:  append([]int(nil), sizes...),
+		counts: make([]int, largest+1),
+		failed: map[string]bool{},
+	}
+	// the largest islands have the fewest ways to be covered, so they are planned first
+	sort.Sort(sort.Reverse(sort.IntSlice(planner.sizes)))
+	for _, length := range lengths {
+		if length <= 0 || length > largest {
+			continue
+		}
+		if planner.counts[length] == 0 {
+			planner.lengths = append(planner.lengths, length)
+		}
+		planner.counts[length]++
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(planner.lengths)))
+	return planner.cover(0)
+}
+
+// islandPlanner assigns words, grouped by length, to the islands one island at a time.
+type islandPlanner struct {
+	sizes []int
+	// distinct word lengths, longest first, and how many unassigned words have each length
+	lengths []int
+	counts  []int
+	// islands that could not be covered with the words left, by island and counts
+	failed map[string]bool
+}
+
+// cover assigns words to the islands from island on.
+func (planner *islandPlanner) cover(island int) bool {
+	if island == len(planner.sizes) {
+		return true
+	}
+	key := fmt.Sprint(island, planner.counts)
+	if planner.failed[key] {
+		return false
+	}
+	if planner.fill(island, planner.sizes[island], 0) {
+		return true
+	}
+	planner.failed[key] = true
+	return false
+}
+
+// fill covers the remaining cells of the island with words of the lengths from lengths[index] on.
+func (planner *islandPlanner) fill(island, remaining, index int) bool {
+	if remaining == 0 {
+		return planner.cover(island + 1)
+	}
+	if index == len(planner.lengths) {
+		return false
+	}
+	length := planner.lengths[index]
+	for count := min(planner.counts[length], remaining/length); count >= 0; count-- {
+		planner.counts[length] -= count
+		covered := planner.fill(island, remaining-count*length, index+1)
+		planner.counts[length] += count
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// canCoverIslands reports whether the unused pool words can cover all islands of empty cells at the same time.
+func (riddle *Riddle) canCoverIslands(islands [][]*Node) bool {
+	sizes := make([]int, len(islands))
+	for i, island := range islands {
+		sizes[i] = len(island)
+	}
+	var lengths []int
+	for _, word := range riddle.Words {
+		if !word.Used {
+			lengths = append(lengths, word.Length())
+		}
+	}
+	return planIslands(sizes, lengths)
+}
+
+// shortestUnusedWord returns the length of the shortest unused pool word besides except,
+// the size of the smallest island of empty cells that can still be filled.
+// Without such a word, it is larger than the grid.
+func (riddle *Riddle) shortestUnusedWord(except *RiddleWord) int {
+	shortest := len(riddle.Nodes) + 1
+	for _, word := range riddle.Words {
+		if !word.Used && word != except && word.Length() > 0 {
+			shortest = min(shortest, word.Length())
+		}
+	}
+	return shortest
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"straenge-riddle-worker/m/random"
+)
+
+func TestPlanLengths(t *testing.T) {
+	plan := planLengths([]int{4, 4, 7}, 20)
+	for cells, want := range map[int]bool{0: true, 4: true, 7: true, 8: true, 11: true, 15: true, 12: false, 10: false, 3: false, 19: false, 21: false, -1: false} {
+		if plan.covers(cells) != want {
+			t.Errorf("covers(%d) = %v, want %v", cells, !want, want)
+		}
+	}
+}
+
+func TestPlanUnusedWords(t *testing.T) {
+	riddle := &Riddle{Words: []*RiddleWord{
+		{Word: "KAFFEE", IsSuperSolution: true, Used: true},
+		{Word: "HUND"},
+		{Word: "MAUS", Used: true},
+		{Word: "ELEFANT"},
+	}}
+	if !riddle.planUnusedWords(11, nil).covers(11) {
+		t.Error("HUND and ELEFANT do not cover 11 cells")
+	}
+	if riddle.planUnusedWords(8, nil).covers(8) {
+		t.Error("the used MAUS is planned")
+	}
+	if riddle.planUnusedWords(11, riddle.Words[1]).covers(11) {
+		t.Error("the excepted HUND is planned")
+	}
+}
+
+func TestPlanIslands(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []int
+		lengths []int
+		want    bool
+	}{
+		{"one island", []int{12}, []int{4, 5, 8}, true},
+		{"islands share no word", []int{9, 4}, []int{4, 4, 5}, true},
+		{"each island alone, but not together", []int{4, 4}, []int{4, 5}, false},
+		{"word needed in two islands", []int{9, 5}, []int{4, 5, 6}, false},
+		{"largest island first", []int{5, 9, 4}, []int{4, 4, 5, 5}, true},
+		{"too few letters", []int{20}, []int{4, 5, 6}, false},
+		{"no islands", nil, []int{4}, true},
+	}
+	for _, test := range tests {
+		if got := planIslands(test.sizes, test.lengths); got != test.want {
+			t.Errorf("%s: planIslands(%v, %v) = %v, want %v", test.name, test.sizes, test.lengths, got, test.want)
+		}
+	}
+}
+
+func TestNewRiddleResamplesPlacement(t *testing.T) {
+	concept := loadSampleConcepts(t)[0].Concept
+	for seed := int64(1); seed <= 20; seed++ {
+		riddle, err := newSampleRiddle(concept, seed)
+		if err != nil {
+			continue
+		}
+		if !riddle.canCoverIslands(riddle.GetAllSubgraphs()) {
+			t.Errorf("seed %d: the word pool cannot fill the subgraphs left by the super solution", seed)
+		}
+	}
+
+	// KAFFEE leaves 10 cells, no subset of 4, 4, 4 and 7 letters adds up to 10
+	_, err := NewRiddle(context.Background(), random.NewSeededRand(1), 4, 4, "Kaffee", []string{"Hund", "Maus", "Esel", "Elefant"})
+	var riddleErr *RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrWordFill || !strings.Contains(riddleErr.Message, "No placement of the super solution") {
+		t.Errorf("NewRiddle() err = %v, want a %s after resampling", err, ErrWordFill)
+	}
+}
+
+func TestFillWithWordsRejectsInfeasibleLengths(t *testing.T) {
+	riddle, err := NewRiddle(context.Background(), random.NewSeededRand(1), 4, 4, "Kaffee", []string{"Tiger", "Zebra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without ZEBRA, the 10 empty cells cannot be filled
+	riddle.Words = riddle.Words[:2]
+	_, err = riddle.FillWithWords(context.Background(), random.NewSeededRand(1))
+	var riddleErr *RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != ErrWordFill || !strings.Contains(riddleErr.Message, "No combination of word lengths") {
+		t.Errorf("FillWithWords() err = %v, want a %s from the length plan", err, ErrWordFill)
+	}
+}
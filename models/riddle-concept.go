@@ -1,7 +1,83 @@
 package models
 
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultMinWordLength is the minimum number of letters of pool words if neither the concept nor the worker set one.
+const DefaultMinWordLength = 4
+
 type RiddleConcept struct {
 	ThemeDescription string   `json:"themeDescription"`
 	SuperSolution    string   `json:"superSolution"`
 	WordPool         []string `json:"wordPool"`
+	// optional grid size, defaults to DefaultRiddleWidth x DefaultRiddleHeight
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// optional limits for the number of letters of pool words, override the worker's limits
+	MinWordLength int `json:"minWordLength,omitempty"`
+	MaxWordLength int `json:"maxWordLength,omitempty"`
+}
+
+// GridSize returns the grid size requested by the concept, falling back to the default size.
+func (concept *RiddleConcept) GridSize() (int, int) {
+	width, height := concept.Width, concept.Height
+	if width == 0 {
+		width = DefaultRiddleWidth
+	}
+	if height == 0 {
+		height = DefaultRiddleHeight
+	}
+	return width, height
+}
+
+// WordLengths limits the number of letters of the pool words placed on a board.
+// A limit of 0 is not set: Min falls back to DefaultMinWordLength, Max to no limit.
+type WordLengths struct {
+	Min int
+	Max int
+}
+
+// WordLengths returns the word length limits of the concept, falling back to the given defaults.
+func (concept *RiddleConcept) WordLengths(defaults WordLengths) WordLengths {
+	lengths := defaults
+	if concept.MinWordLength != 0 {
+		lengths.Min = concept.MinWordLength
+	}
+	if concept.MaxWordLength != 0 {
+		lengths.Max = concept.MaxWordLength
+	}
+	if lengths.Min == 0 {
+		lengths.Min = DefaultMinWordLength
+	}
+	return lengths
+}
+
+// Allows reports whether a word with this number of letters may be placed.
+func (lengths WordLengths) Allows(length int) bool {
+	return length >= lengths.Min && (lengths.Max == 0 || length <= lengths.Max)
+}
+
+// Valid reports whether the limits allow any word of at least two letters.
+func (lengths WordLengths) Valid() bool {
+	return lengths.Min >= 2 && (lengths.Max == 0 || lengths.Max >= lengths.Min)
+}
+
+func (lengths WordLengths) String() string {
+	if lengths.Max == 0 {
+		return fmt.Sprintf("at least %d letters", lengths.Min)
+	}
+	return fmt.Sprintf("%d to %d letters", lengths.Min, lengths.Max)
+}
+
+// Filter returns the words whose length on the board is within the limits.
+func (lengths WordLengths) Filter(words []string) []string {
+	var allowed []string
+	for _, word := range words {
+		if lengths.Allows(utf8.RuneCountInString(MakeWordSafe(word))) {
+			allowed = append(allowed, word)
+		}
+	}
+	return allowed
 }
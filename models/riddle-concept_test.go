@@ -0,0 +1,44 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordLengths(t *testing.T) {
+	tests := []struct {
+		name     string
+		concept  RiddleConcept
+		defaults WordLengths
+		want     WordLengths
+	}{
+		{"defaults", RiddleConcept{}, WordLengths{}, WordLengths{Min: DefaultMinWordLength}},
+		{"worker limits", RiddleConcept{}, WordLengths{Min: 3, Max: 8}, WordLengths{Min: 3, Max: 8}},
+		{"concept overrides the worker", RiddleConcept{MinWordLength: 5}, WordLengths{Min: 3, Max: 8}, WordLengths{Min: 5, Max: 8}},
+		{"concept sets only a maximum", RiddleConcept{MaxWordLength: 6}, WordLengths{}, WordLengths{Min: DefaultMinWordLength, Max: 6}},
+	}
+	for _, test := range tests {
+		if got := test.concept.WordLengths(test.defaults); got != test.want {
+			t.Errorf("%s: WordLengths() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	lengths := WordLengths{Min: 4, Max: 6}
+	if lengths.Allows(3) || !lengths.Allows(4) || !lengths.Allows(6) || lengths.Allows(7) {
+		t.Errorf("%s allows the wrong lengths", lengths)
+	}
+	if !(WordLengths{Min: 4}).Allows(40) {
+		t.Error("a maximum of 0 limits the length")
+	}
+	// Straße has 6 letters on the board, STRAẞE
+	got := lengths.Filter([]string{"Kuh", "Hund", "Straße", "Schwein"})
+	if want := []string{"Hund", "Straße"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter() = %q, want %q", got, want)
+	}
+
+	for _, invalid := range []WordLengths{{Min: 1}, {Min: 5, Max: 4}, {Min: 4, Max: -1}} {
+		if invalid.Valid() {
+			t.Errorf("%+v is valid", invalid)
+		}
+	}
+}
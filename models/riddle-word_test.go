@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestMakeWordSafe(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"Bauernhof", "BAUERNHOF"},
+		{"Sankt Martin", "SANKTMARTIN"},
+		{"Baden-Württemberg", "BADENWÜRTTEMBERG"},
+		{"Straße", "STRAẞE"},
+		{"ẞ", "ẞ"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := MakeWordSafe(test.word); got != test.want {
+			t.Errorf("MakeWordSafe(%q) = %q, want %q", test.word, got, test.want)
+		}
+	}
+}
+
+func TestRiddleWordLetters(t *testing.T) {
+	word := &RiddleWord{Word: MakeWordSafe("Küche")}
+	if word.Length() != 5 {
+		t.Errorf("Length() = %d, want 5", word.Length())
+	}
+	if word.RuneAt(1) != 'Ü' {
+		t.Errorf("RuneAt(1) = %q, want 'Ü'", word.RuneAt(1))
+	}
+	// the cached letters follow changes of the word
+	word.Word = "OFEN"
+	if word.Length() != 4 || word.RuneAt(0) != 'O' {
+		t.Errorf("letters not updated after changing the word: %q", string(word.Letters()))
+	}
+}
@@ -1,47 +1,75 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"math/rand"
 	"sort"
 	"straenge-riddle-worker/m/defaults/colors"
-	"straenge-riddle-worker/m/random"
 	"strconv"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 )
 
+// superSolutionPlacements is how often NewRiddle places the super solution until the word pool can fill the remaining subgraphs.
+const superSolutionPlacements = 50
+
+// default grid size, used if a riddle concept does not ask for another one
 const (
-	RiddleWidth  int = 6
-	RiddleHeight int = 8
+	DefaultRiddleWidth  int = 6
+	DefaultRiddleHeight int = 8
 )
 
 type Riddle struct {
-	Nodes []*Node       `json:"nodes"`
-	Words []*RiddleWord `json:"words"`
-	Edges []*LetterEdge `json:"edges"`
+	Width  int           `json:"width"`
+	Height int           `json:"height"`
+	Nodes  []*Node       `json:"nodes"`
+	Words  []*RiddleWord `json:"words"`
+	Edges  []*LetterEdge `json:"edges"`
+	// undo trail of the search, see mark and undoTo
+	filledNodes []*Node
+	usedWords   []*RiddleWord
+	// shared adjacency of the grid size and the diagonal drawn in every 2x2 block, see grid
+	topology  *gridTopology
+	diagonals []uint8
+}
+
+// boardMark is a position in the undo trail of a riddle.
+// The search fills nodes on a single board and rolls back to a mark instead of copying the riddle.
+type boardMark struct {
+	filledNodes int
+	edges       int
+	usedWords   int
 }
 
 func NewRiddleFromConfig(riddleConfig *RiddleConfig) *Riddle {
 	availableColors := []string{colors.Blue, colors.Cyan, colors.Gray, colors.Green, colors.Magenta, colors.Red, colors.Yellow}
-	var riddle = &Riddle{
-		Nodes: make([]*Node, RiddleWidth*RiddleHeight),
-		Words: []*RiddleWord{},
-		Edges: []*LetterEdge{},
+	height := len(riddleConfig.Letters)
+	width := 0
+	if height > 0 {
+		width = len(riddleConfig.Letters[0])
 	}
-	for i := 0; i < RiddleHeight; i++ {
-		for j := 0; j < RiddleWidth; j++ {
-			riddle.Nodes[i*RiddleWidth+j] = &Node{
+	var riddle = &Riddle{
+		Width:  width,
+		Height: height,
+		Nodes:  make([]*Node, width*height),
+		Words:  []*RiddleWord{},
+		Edges:  []*LetterEdge{},
+	}
+	for i := 0; i < height; i++ {
+		for j := 0; j < width; j++ {
+			riddle.Nodes[i*width+j] = &Node{
 				Row: i,
 				Col: j,
 			}
 		}
 	}
-	for _, solution := range riddleConfig.Solutions {
+	for index, solution := range riddleConfig.Solutions {
 		var color = colors.White
 		if !solution.IsSuperSolution {
-			color = availableColors[rand.Intn(len(availableColors))]
+			color = availableColors[index%len(availableColors)]
 		}
 		// can't use solutionWord := solution.Word because some solutions don't have a word
 		// instead, get it by the solution.Locations and the letters in the riddle
@@ -61,7 +89,7 @@ func NewRiddleFromConfig(riddleConfig *RiddleConfig) *Riddle {
 		}
 		// make edges
 		for i := 0; i < len(solution.Locations)-1; i++ {
-			riddle.Edges = append(riddle.Edges, &LetterEdge{
+			riddle.addEdge(&LetterEdge{
 				Word:  word,
 				Node1: riddle.GetNode(solution.Locations[i].Row, solution.Locations[i].Col),
 				Node2: riddle.GetNode(solution.Locations[i+1].Row, solution.Locations[i+1].Col),
@@ -71,14 +99,41 @@ func NewRiddleFromConfig(riddleConfig *RiddleConfig) *Riddle {
 	return riddle
 }
 
-func NewRiddle(superSolution string, words []string) (*Riddle, error) {
-	if len(superSolution) < 6 {
+func NewRiddle(ctx context.Context, rng *rand.Rand, width, height int, superSolution string, words []string) (*Riddle, error) {
+	riddle, err := newEmptyRiddle(width, height, superSolution, words)
+	if err != nil {
+		return nil, err
+	}
+	// a placement that splits the grid into islands the word pool cannot fill is replaced right away,
+	// instead of failing the whole attempt in FillWithWords
+	for placement := 1; ; placement++ {
+		start := riddle.mark()
+		if err := riddle.FillWord(ctx, rng, riddle.Words[0], riddle.Nodes); err != nil {
+			return nil, err
+		}
+		if riddle.canCoverIslands(riddle.GetAllSubgraphs()) {
+			return riddle, nil
+		}
+		riddle.undoTo(start)
+		if placement == superSolutionPlacements {
+			return nil, &RiddleError{ErrType: ErrWordFill, Message: "No placement of the super solution leaves subgraphs the word pool can fill"}
+		}
+		SuperSolutionResamples.Add(1)
+		logrus.Debug("[NewRiddle] Word pool cannot fill the subgraphs, placing super solution again")
+	}
+}
+
+// newEmptyRiddle creates a riddle without letters, with the super solution as first word and the pool words unused.
+func newEmptyRiddle(width, height int, superSolution string, words []string) (*Riddle, error) {
+	if utf8.RuneCountInString(MakeWordSafe(superSolution)) < MinSuperSolutionLength {
 		return nil, &RiddleError{ErrType: ErrWordLength, Message: "Super solution word too short"}
 	}
 	var riddle = &Riddle{
-		Nodes: make([]*Node, RiddleWidth*RiddleHeight),
-		Words: []*RiddleWord{},
-		Edges: []*LetterEdge{},
+		Width:  width,
+		Height: height,
+		Nodes:  make([]*Node, width*height),
+		Words:  []*RiddleWord{},
+		Edges:  []*LetterEdge{},
 	}
 	riddle.Words = append(riddle.Words, &RiddleWord{
 		Word:            MakeWordSafe(superSolution),
@@ -97,46 +152,105 @@ func NewRiddle(superSolution string, words []string) (*Riddle, error) {
 			Used:            false,
 		})
 	}
-	for row := 0; row < RiddleHeight; row++ {
-		for col := 0; col < RiddleWidth; col++ {
-			riddle.Nodes[row*RiddleWidth+col] = &Node{
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			riddle.Nodes[row*width+col] = &Node{
 				Row: row,
 				Col: col,
 			}
 		}
 	}
-	riddle, error := riddle.FillWord(riddle.Words[0], riddle.Nodes)
-	return riddle, error
+	return riddle, nil
 }
 
+// Copy returns a deep copy of the riddle, with nodes and edges referring to the copied words and nodes.
 func (riddle *Riddle) Copy() *Riddle {
 	var newRiddle = &Riddle{
-		Nodes: make([]*Node, RiddleWidth*RiddleHeight),
-		Words: make([]*RiddleWord, len(riddle.Words)),
-		Edges: riddle.Edges,
+		Width:  riddle.Width,
+		Height: riddle.Height,
+		Nodes:  make([]*Node, len(riddle.Nodes)),
+		Words:  make([]*RiddleWord, len(riddle.Words)),
+		Edges:  make([]*LetterEdge, len(riddle.Edges)),
+	}
+	var copiedWords = make(map[*RiddleWord]*RiddleWord, len(riddle.Words))
+	for i, word := range riddle.Words {
+		newRiddle.Words[i] = &RiddleWord{
+			Word:            word.Word,
+			IsSuperSolution: word.IsSuperSolution,
+			Color:           word.Color,
+			Used:            word.Used,
+		}
+		copiedWords[word] = newRiddle.Words[i]
 	}
 	for i, node := range riddle.Nodes {
-		// log the node
 		newRiddle.Nodes[i] = &Node{
 			Row:             node.Row,
 			Col:             node.Col,
-			RiddleWord:      node.RiddleWord,
+			RiddleWord:      copiedWords[node.RiddleWord],
 			RiddleWordIndex: node.RiddleWordIndex,
 		}
 	}
-	for i, word := range riddle.Words {
-		newRiddle.Words[i] = &RiddleWord{
-			Word:            word.Word,
-			IsSuperSolution: word.IsSuperSolution,
-			Color:           word.Color,
-			Used:            word.Used,
+	for i, edge := range riddle.Edges {
+		newRiddle.Edges[i] = &LetterEdge{
+			Word:  copiedWords[edge.Word],
+			Node1: newRiddle.GetNode(edge.Node1.Row, edge.Node1.Col),
+			Node2: newRiddle.GetNode(edge.Node2.Row, edge.Node2.Col),
 		}
 	}
 	return newRiddle
 }
 
-func (riddle *Riddle) FillWithWords() (*Riddle, error) {
-	subgraphsToFill := riddle.GetAllSubgraphs()
+func (riddle *Riddle) mark() boardMark {
+	return boardMark{
+		filledNodes: len(riddle.filledNodes),
+		edges:       len(riddle.Edges),
+		usedWords:   len(riddle.usedWords),
+	}
+}
+
+// undoTo rolls back every node, edge and used word added since the mark was taken.
+func (riddle *Riddle) undoTo(mark boardMark) {
+	for _, node := range riddle.filledNodes[mark.filledNodes:] {
+		node.RiddleWord = nil
+		node.RiddleWordIndex = 0
+	}
+	for _, word := range riddle.usedWords[mark.usedWords:] {
+		word.Used = false
+	}
+	if riddle.topology != nil {
+		for _, edge := range riddle.Edges[mark.edges:] {
+			riddle.setDiagonal(edge, false)
+		}
+	}
+	clear(riddle.Edges[mark.edges:])
+	riddle.filledNodes = riddle.filledNodes[:mark.filledNodes]
+	riddle.Edges = riddle.Edges[:mark.edges]
+	riddle.usedWords = riddle.usedWords[:mark.usedWords]
+}
+
+// placeLetter fills a node with a letter of a word during the search and connects it to the previous letter.
+func (riddle *Riddle) placeLetter(node *Node, word *RiddleWord, index int, previousNode *Node) {
+	node.RiddleWord = word
+	node.RiddleWordIndex = index
+	riddle.filledNodes = append(riddle.filledNodes, node)
+	if previousNode != nil {
+		riddle.addEdge(&LetterEdge{
+			Word:  word,
+			Node1: previousNode,
+			Node2: node,
+		})
+	}
+}
+
+func (riddle *Riddle) markWordUsed(word *RiddleWord) {
+	word.Used = true
+	riddle.usedWords = append(riddle.usedWords, word)
+}
+
+// FillWithWords fills all empty nodes of a copy of the riddle with words from the pool and returns the copy.
+func (riddle *Riddle) FillWithWords(ctx context.Context, rng *rand.Rand) (*Riddle, error) {
+	updatedRiddle := riddle.Copy()
+	subgraphsToFill := updatedRiddle.GetAllSubgraphs()
 	// sort subgraphs by size ascending
 	for i := 0; i < len(subgraphsToFill); i++ {
 		for j := i + 1; j < len(subgraphsToFill); j++ {
@@ -146,85 +260,105 @@ func (riddle *Riddle) FillWithWords() (*Riddle, error) {
 		}
 	}
 
-	updatedRiddle := riddle.Copy()
+	// reject boards whose islands cannot be covered by the word lengths of the pool before searching
+	if !updatedRiddle.canCoverIslands(subgraphsToFill) {
+		return nil, &RiddleError{ErrType: ErrWordFill, Message: "No combination of word lengths fills all " + strconv.Itoa(len(subgraphsToFill)) + " subgraphs"}
+	}
+
 	logrus.Debug("[FillWithWords] Subgraphs to fill: ", len(subgraphsToFill))
 	for index, subgraph := range subgraphsToFill {
 		logrus.Debug("[FillWithWords] Filling subgraph " + strconv.Itoa(index) + "/" + strconv.Itoa(len(subgraphsToFill)-1))
-		riddleWithFilledSubgraph, error := updatedRiddle.fillSubgraphRecursive(0, subgraph)
-		if error != nil {
-			return nil, error
+		if err := updatedRiddle.fillSubgraphRecursive(ctx, rng, 0, subgraph); err != nil {
+			return nil, err
 		}
-		updatedRiddle = riddleWithFilledSubgraph
 	}
+	updatedRiddle.filledNodes = nil
+	updatedRiddle.usedWords = nil
 	return updatedRiddle, nil
 }
 
-func (riddle *Riddle) fillSubgraphRecursive(depth int, subgraph []*Node) (*Riddle, error) {
+// fillSubgraphRecursive fills the subgraph with unused words.
+// On failure, the board is rolled back to the state before the call.
+func (riddle *Riddle) fillSubgraphRecursive(ctx context.Context, rng *rand.Rand, depth int, subgraph []*Node) error {
+	if err := checkCanceled(ctx); err != nil {
+		return err
+	}
 	logrus.Debug("[fillSubgraphRecursive("+strconv.Itoa(depth)+")] Trying to fill subgraph with length ", len(subgraph))
 	availableWords := []*RiddleWord{}
 	for _, word := range riddle.Words {
 		wordLength := word.Length()
-		if !word.Used && (wordLength <= len(subgraph)-4 || wordLength == len(subgraph)) {
-			availableWords = append(availableWords, word)
+		// the rest of the subgraph must leave room for at least the shortest other word
+		if word.Used || !(wordLength <= len(subgraph)-riddle.shortestUnusedWord(word) || wordLength == len(subgraph)) {
+			continue
+		}
+		// only try words after which the other unused words can still cover the rest of the subgraph exactly
+		if wordLength < len(subgraph) && !riddle.planUnusedWords(len(subgraph)-wordLength, word).covers(len(subgraph)-wordLength) {
+			continue
 		}
+		availableWords = append(availableWords, word)
 	}
 	if len(availableWords) == 0 {
-		return nil, &RiddleError{ErrType: ErrWordFill, Message: "No available words to fill subgraph of size " + strconv.Itoa(len(subgraph))}
+		return &RiddleError{ErrType: ErrWordFill, Message: "No available words to fill subgraph of size " + strconv.Itoa(len(subgraph))}
 	}
 	// randomize order of available words
 	for i := range availableWords {
-		j := random.NewSafeRand().Intn(i + 1)
+		j := rng.Intn(i + 1)
 		availableWords[i], availableWords[j] = availableWords[j], availableWords[i]
 	}
 	logrus.Debug("[fillSubgraphRecursive("+strconv.Itoa(depth)+")] availableWord count: ", len(availableWords))
+	start := riddle.mark()
 	for _, word := range availableWords {
 		logrus.Debug("[fillSubgraphRecursive("+strconv.Itoa(depth)+")] trying with word: ", word.Word)
-		riddleWithWordFilled, err := riddle.FillWord(word, subgraph)
+		err := riddle.FillWord(ctx, rng, word, subgraph)
+		if IsCanceled(err) {
+			return err
+		}
 		if err != nil {
 			logrus.Debug("[fillSubgraphRecursive("+strconv.Itoa(depth)+")] failed to fill word: ", word.Word)
 			logrus.Debug(err)
+			continue
 		}
-		if err == nil {
-			for _, riddleWord := range riddleWithWordFilled.Words {
-				if riddleWord.Word == word.Word {
-					riddleWord.Used = true
-					break
-				}
-			}
-			riddleWithWordFilled.Render(true)
-			subgraphs := riddleWithWordFilled.GetAllSubgraphs()
-
-			// filter subgraphs: only keep those that are part of the original subgraph
-			var filteredSubgraphs [][]*Node
-			for _, subgraphToCheck := range subgraphs {
-				if ContainsNodePosition(subgraph, subgraphToCheck[0]) {
-					filteredSubgraphs = append(filteredSubgraphs, subgraphToCheck)
-				}
-			}
-			if len(filteredSubgraphs) == 1 {
-				return riddleWithWordFilled.fillSubgraphRecursive(depth+1, filteredSubgraphs[0])
+		riddle.markWordUsed(word)
+		riddle.Render(true)
+		subgraphs := riddle.GetAllSubgraphs()
+
+		// filter subgraphs: only keep those that are part of the original subgraph
+		var filteredSubgraphs [][]*Node
+		for _, subgraphToCheck := range subgraphs {
+			if ContainsNodePosition(subgraph, subgraphToCheck[0]) {
+				filteredSubgraphs = append(filteredSubgraphs, subgraphToCheck)
 			}
-			// if there are multiple subgraphs, try to fill them all
-			currentRiddleCopy := riddleWithWordFilled.Copy()
-			for _, filteredSubgraph := range filteredSubgraphs {
-				var nextRiddleTry *Riddle
-				nextRiddleTry, err = currentRiddleCopy.fillSubgraphRecursive(depth+1, filteredSubgraph)
-				if err == nil {
-					currentRiddleCopy = nextRiddleTry
-				} else {
-					break
-				}
+		}
+		if len(filteredSubgraphs) == 1 {
+			err = riddle.fillSubgraphRecursive(ctx, rng, depth+1, filteredSubgraphs[0])
+			if err != nil {
+				riddle.undoTo(start)
 			}
-			if err == nil {
-				return currentRiddleCopy, nil
+			return err
+		}
+		// if there are multiple subgraphs, try to fill them all
+		for _, filteredSubgraph := range filteredSubgraphs {
+			err = riddle.fillSubgraphRecursive(ctx, rng, depth+1, filteredSubgraph)
+			if err != nil {
+				break
 			}
 		}
+		if err == nil {
+			return nil
+		}
+		riddle.undoTo(start)
+		if IsCanceled(err) {
+			return err
+		}
 	}
-	return nil, &RiddleError{ErrType: ErrWordFill, Message: "No possible fill found for subgraph of size " + strconv.Itoa(len(subgraph))}
+	return &RiddleError{ErrType: ErrWordFill, Message: "No possible fill found for subgraph of size " + strconv.Itoa(len(subgraph))}
 }
 
-func (riddle *Riddle) FillWord(word *RiddleWord, subgraph []*Node) (*Riddle, error) {
-	return riddle.fillWordRecursive(0, word, subgraph, 0, nil, nil, false)
+// FillWord places the word along a path of nodes in the subgraph, directly on the riddle.
+// Islands of empty cells it cuts off must be large enough for the shortest other unused word.
+// On failure, the board is left unchanged.
+func (riddle *Riddle) FillWord(ctx context.Context, rng *rand.Rand, word *RiddleWord, subgraph []*Node) error {
+	return riddle.fillWordRecursive(ctx, rng, 0, word, subgraph, riddle.shortestUnusedWord(word), 0, nil, nil, false)
 }
 
 func isEdgeReachable(node *Node, rowToReach, colToReach int, remainingSteps int) bool {
@@ -243,33 +377,39 @@ func isEdgeReachable(node *Node, rowToReach, colToReach int, remainingSteps int)
 	}
 }
 
-func (riddle *Riddle) fillWordRecursive(depth int, word *RiddleWord, subgraph []*Node, index int, firstNode *Node, previousNode *Node, touchedOppositeEdge bool) (*Riddle, error) {
+func (riddle *Riddle) fillWordRecursive(ctx context.Context, rng *rand.Rand, depth int, word *RiddleWord, subgraph []*Node, minimumIslandSize int, index int, firstNode *Node, previousNode *Node, touchedOppositeEdge bool) error {
+	if err := checkCanceled(ctx); err != nil {
+		return err
+	}
 	wordLength := word.Length()
 	logrus.Debug("[fillWordRecursive("+strconv.Itoa(depth)+")] Trying to fill word ", word.Word, "(l=", wordLength, ")[i=", index, "] into subgraph with length ", len(subgraph))
 	if index == wordLength {
-		return riddle, nil
+		return nil
 	}
 	if len(subgraph) < wordLength-index {
-		return nil, &RiddleError{ErrType: ErrWordFill, Message: "Subgraph too small"}
+		return &RiddleError{ErrType: ErrWordFill, Message: "Subgraph too small"}
 	}
 	if firstNode == nil {
 		firstNode = previousNode
 	}
-	if word.IsSuperSolution && index != 0 && (previousNode.Row == 0 && firstNode.Row == RiddleHeight-1 || previousNode.Row == RiddleHeight-1 && firstNode.Row == 0 || previousNode.Col == 0 && firstNode.Col == RiddleWidth-1 || previousNode.Col == RiddleWidth-1 && firstNode.Col == 0) {
+	if word.IsSuperSolution && index != 0 && (previousNode.Row == 0 && firstNode.Row == riddle.Height-1 || previousNode.Row == riddle.Height-1 && firstNode.Row == 0 || previousNode.Col == 0 && firstNode.Col == riddle.Width-1 || previousNode.Col == riddle.Width-1 && firstNode.Col == 0) {
 		touchedOppositeEdge = true
 		logrus.Debug("Touched opposite edge")
 	}
 	var possibleNodes []*Node = []*Node{}
-	minimumRemainingSubgraphSize := 4
+	minimumRemainingSubgraphSize := minimumIslandSize
 	remainingLetterCount := wordLength - index
 	if remainingLetterCount == len(subgraph) {
 		minimumRemainingSubgraphSize = remainingLetterCount - 1
 	}
 	if index == 0 {
 		for _, node := range subgraph {
+			if err := checkCanceled(ctx); err != nil {
+				return err
+			}
 			if riddle.NodeCanBeFilled(word, node, nil, minimumRemainingSubgraphSize) {
 				if word.IsSuperSolution {
-					if !(node.Row == 0 || node.Row == RiddleHeight-1 || node.Col == 0 || node.Col == RiddleWidth-1) {
+					if !(node.Row == 0 || node.Row == riddle.Height-1 || node.Col == 0 || node.Col == riddle.Width-1) {
 						continue
 					}
 				}
@@ -285,12 +425,12 @@ func (riddle *Riddle) fillWordRecursive(depth int, word *RiddleWord, subgraph []
 				if !touchedOppositeEdge {
 					var rowToReach, colToReach int = -1, -1
 					if firstNode.Row == 0 {
-						rowToReach = RiddleHeight - 1
-					} else if firstNode.Row == RiddleHeight-1 {
+						rowToReach = riddle.Height - 1
+					} else if firstNode.Row == riddle.Height-1 {
 						rowToReach = 0
 					} else if firstNode.Col == 0 {
-						colToReach = RiddleWidth - 1
-					} else if firstNode.Col == RiddleWidth-1 {
+						colToReach = riddle.Width - 1
+					} else if firstNode.Col == riddle.Width-1 {
 						colToReach = 0
 					}
 					logrus.Debug("isEdgeReachable(", node.Row, ",", node.Col, ",", rowToReach, ",", colToReach, ",", remainingLetterCount-1, ") = ", isEdgeReachable(node, rowToReach, colToReach, remainingLetterCount-1))
@@ -303,27 +443,19 @@ func (riddle *Riddle) fillWordRecursive(depth int, word *RiddleWord, subgraph []
 		}
 	}
 	if len(possibleNodes) == 0 {
-		return nil, &RiddleError{ErrType: ErrWordFill, Message: "No possible nodes to fill word"}
+		return &RiddleError{ErrType: ErrWordFill, Message: "No possible nodes to fill word"}
 	}
 	// randomize order of possible nodes
 	for i := range possibleNodes {
-		j := random.NewSafeRand().Intn(i + 1)
+		j := rng.Intn(i + 1)
 		possibleNodes[i], possibleNodes[j] = possibleNodes[j], possibleNodes[i]
 	}
 	// depth first try to fill the word with possible nodes
 	var lastErr error
+	start := riddle.mark()
 	for _, node := range possibleNodes {
 		logrus.Debug("[fillWordRecursive("+strconv.Itoa(depth)+")] Trying to use node ", node.Row, ",", node.Col)
-		riddleCopy := riddle.Copy()
-		riddleCopy.FillNode(node.Row, node.Col, word, index)
-		if previousNode != nil {
-			var drawnEdge = &LetterEdge{
-				Word:  word,
-				Node1: previousNode,
-				Node2: node,
-			}
-			riddleCopy.Edges = append(riddleCopy.Edges, drawnEdge)
-		}
+		riddle.placeLetter(node, word, index, previousNode)
 		// fill the rest of the word
 		var nextSubgraph []*Node = []*Node{}
 		for _, subgraphNode := range subgraph {
@@ -331,14 +463,19 @@ func (riddle *Riddle) fillWordRecursive(depth int, word *RiddleWord, subgraph []
 				nextSubgraph = append(nextSubgraph, subgraphNode)
 			}
 		}
-		riddleCopy, lastErr = riddleCopy.fillWordRecursive(depth+1, word, nextSubgraph, index+1, firstNode, node, touchedOppositeEdge)
+		lastErr = riddle.fillWordRecursive(ctx, rng, depth+1, word, nextSubgraph, minimumIslandSize, index+1, firstNode, node, touchedOppositeEdge)
 		if lastErr == nil {
 			logrus.Debug("[fillWordRecursive("+strconv.Itoa(depth)+")] Successfully filled word ", word.Word, "(l=", wordLength, ") into subgraph with length ", len(subgraph))
-			return riddleCopy, nil
+			return nil
+		}
+		riddle.undoTo(start)
+		if IsCanceled(lastErr) {
+			return lastErr
 		}
+		FillBacktracks.Add(1)
 		logrus.Debug("[fillWordRecursive("+strconv.Itoa(depth)+")] Failed using this node because: ", lastErr.Error())
 	}
-	return nil, &RiddleError{ErrType: ErrWordFill, Message: "No possible fill path found, inner error: " + lastErr.Error()}
+	return &RiddleError{ErrType: ErrWordFill, Message: "No possible fill path found, inner error: " + lastErr.Error()}
 }
 
 func (riddle *Riddle) FillNode(row, col int, riddleWord *RiddleWord, riddleWordIndex int) {
@@ -347,142 +484,76 @@ func (riddle *Riddle) FillNode(row, col int, riddleWord *RiddleWord, riddleWordI
 }
 
 func (riddle *Riddle) GetNode(row, col int) *Node {
-	return riddle.Nodes[row*RiddleWidth+col]
+	return riddle.Nodes[row*riddle.Width+col]
 }
 
 func (riddle *Riddle) NodeIsInBounds(row, col int) bool {
-	return row >= 0 && row < RiddleHeight && col >= 0 && col < RiddleWidth
+	return row >= 0 && row < riddle.Height && col >= 0 && col < riddle.Width
 }
 
 func (riddle *Riddle) GetAllSubgraphs() [][]*Node {
 	var subgraphs [][]*Node = [][]*Node{}
-	for _, node := range riddle.Nodes {
-		if !node.isEmpty() {
+	var inSubgraph = make([]bool, len(riddle.Nodes))
+	for index, node := range riddle.Nodes {
+		if !node.isEmpty() || inSubgraph[index] {
 			continue
 		}
-		nodeAlreadyInSubgraphs := false
-		for _, subgraph := range subgraphs {
-			if ContainsNodePosition(subgraph, node) {
-				nodeAlreadyInSubgraphs = true
-				break
-			}
-		}
-		if !nodeAlreadyInSubgraphs {
-			subgraph := riddle.GetConnectedSubgraph(node)
-			subgraphs = append(subgraphs, subgraph)
+		subgraph := riddle.GetConnectedSubgraph(node)
+		for _, subgraphNode := range subgraph {
+			inSubgraph[subgraphNode.Row*riddle.Width+subgraphNode.Col] = true
 		}
+		subgraphs = append(subgraphs, subgraph)
 	}
 	return subgraphs
 }
 
+// GetConnectedSubgraph returns the empty nodes reachable from node in visiting order,
+// so the result does not depend on anything but the board.
 func (riddle *Riddle) GetConnectedSubgraph(node *Node) []*Node {
-	var connectedNodes = make(map[*Node]bool)
-	var nodesToCheck = []*Node{node}
-	for len(nodesToCheck) > 0 {
-		var currentNode = nodesToCheck[0]
-		nodesToCheck = nodesToCheck[1:]
-		connectedNodes[currentNode] = true
-		emptyAdjacentNodes := riddle.GetEmptyAdjacentNodes(currentNode)
-		for _, adjacentNode := range emptyAdjacentNodes {
-			if !connectedNodes[adjacentNode] && !HasOverlappingEdges(append(riddle.Edges, &LetterEdge{Node1: currentNode, Node2: adjacentNode})) {
-				nodesToCheck = append(nodesToCheck, adjacentNode)
+	topology := riddle.grid()
+	var visited = make([]bool, len(riddle.Nodes))
+	var connectedNodes = []*Node{node}
+	visited[node.Row*riddle.Width+node.Col] = true
+	for next := 0; next < len(connectedNodes); next++ {
+		currentNode := connectedNodes[next]
+		for _, index := range topology.neighbors[currentNode.Row*riddle.Width+currentNode.Col] {
+			adjacentNode := riddle.Nodes[index]
+			if visited[index] || !adjacentNode.isEmpty() || riddle.crossesDiagonal(currentNode, adjacentNode) {
+				continue
 			}
+			visited[index] = true
+			connectedNodes = append(connectedNodes, adjacentNode)
 		}
 	}
-	var connectedNodesSlice []*Node
-	for node := range connectedNodes {
-		connectedNodesSlice = append(connectedNodesSlice, node)
-	}
-	return connectedNodesSlice
+	return connectedNodes
 }
 
 func (riddle *Riddle) GetConnectedSubgraphSize(node *Node) int {
 	return len(riddle.GetConnectedSubgraph(node))
 }
 
+// DoesNotOverlapWithEdges reports whether an edge between the adjacent nodes node and next would not cross any existing edge.
+// Two edges can only cross as the two diagonals of a 2x2 block, so this is a lookup of the diagonal drawn in that block.
 func (riddle *Riddle) DoesNotOverlapWithEdges(node *Node, next *Node) bool {
-	var newEdge = &LetterEdge{
-		Node1: node,
-		Node2: next,
-	}
-	return !HasOverlappingEdges(append(riddle.Edges, newEdge))
+	return !riddle.crossesDiagonal(node, next)
 }
 
 func (riddle *Riddle) GetEmptyAdjacentNodes(node *Node) []*Node {
-	var allAdjacentNodes = riddle.GetAdjacentNodes(node, false)
 	var emptyAdjacentNodes []*Node
-	for _, adjacentNode := range allAdjacentNodes {
-		if adjacentNode.isEmpty() {
+	for _, index := range riddle.grid().neighbors[node.Row*riddle.Width+node.Col] {
+		adjacentNode := riddle.Nodes[index]
+		if adjacentNode.isEmpty() && !riddle.crossesDiagonal(node, adjacentNode) {
 			emptyAdjacentNodes = append(emptyAdjacentNodes, adjacentNode)
 		}
 	}
 	return emptyAdjacentNodes
 }
 
-func (riddle *Riddle) getAdjacentNodesWithLetter(node *Node, letter rune, nodesToIgnore []*Node) []*Node {
-	var allAdjacentNodes = riddle.GetAdjacentNodes(node, true)
-	var matchingAdjacentNodes []*Node
-	for _, adjacentNode := range allAdjacentNodes {
-		for _, ignoredNode := range nodesToIgnore {
-			if adjacentNode.Row == ignoredNode.Row && adjacentNode.Col == ignoredNode.Col {
-				continue
-			}
-		}
-		if adjacentNode.RiddleWord != nil && adjacentNode.RiddleWord.RuneAt(adjacentNode.RiddleWordIndex) == letter {
-			matchingAdjacentNodes = append(matchingAdjacentNodes, adjacentNode)
-		}
-	}
-	return matchingAdjacentNodes
-}
-
 func (riddle *Riddle) GetAdjacentNodes(node *Node, ignoreEdges bool) []*Node {
-	var row, col = node.Row, node.Col
 	var adjacentNodes []*Node
-	if riddle.NodeIsInBounds(row-1, col) {
-		var potentialNode = riddle.GetNode(row-1, col)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row+1, col) {
-		var potentialNode = riddle.GetNode(row+1, col)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row, col-1) {
-		var potentialNode = riddle.GetNode(row, col-1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row, col+1) {
-		var potentialNode = riddle.GetNode(row, col+1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row-1, col-1) {
-		var potentialNode = riddle.GetNode(row-1, col-1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row-1, col+1) {
-		var potentialNode = riddle.GetNode(row-1, col+1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row+1, col-1) {
-		var potentialNode = riddle.GetNode(row+1, col-1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
-			adjacentNodes = append(adjacentNodes, potentialNode)
-		}
-	}
-	if riddle.NodeIsInBounds(row+1, col+1) {
-		var potentialNode = riddle.GetNode(row+1, col+1)
-		if ignoreEdges || riddle.DoesNotOverlapWithEdges(node, potentialNode) {
+	for _, index := range riddle.grid().neighbors[node.Row*riddle.Width+node.Col] {
+		var potentialNode = riddle.Nodes[index]
+		if ignoreEdges || !riddle.crossesDiagonal(node, potentialNode) {
 			adjacentNodes = append(adjacentNodes, potentialNode)
 		}
 	}
@@ -500,27 +571,26 @@ func (riddle *Riddle) NodeCanBeFilled(riddleWord *RiddleWord, node *Node, coming
 		return false
 	}
 
-	riddleCopy := riddle.Copy()
-	riddleCopy.Nodes[node.Row*RiddleWidth+node.Col].RiddleWord = riddleWord
-
+	// fill the node on the board for the check and roll it back afterwards
+	start := riddle.mark()
+	node.RiddleWord = riddleWord
 	if comingFrom != nil {
-		riddleCopy.Edges = append(riddleCopy.Edges, &LetterEdge{
+		riddle.addEdge(&LetterEdge{
 			Word:  riddleWord,
 			Node1: comingFrom,
 			Node2: node,
 		})
 	}
+	defer func() {
+		node.RiddleWord = nil
+		riddle.undoTo(start)
+	}()
 
-	emptyAdjacentNodes := riddleCopy.GetEmptyAdjacentNodes(riddleCopy.GetNode(node.Row, node.Col))
+	emptyAdjacentNodes := riddle.GetEmptyAdjacentNodes(node)
 
-	if len(emptyAdjacentNodes) > 0 {
-		// fmt.Printf("Checking subgraph size for node %d,%d\n", node.Row, node.Col)
-		for _, adjacentNode := range emptyAdjacentNodes {
-			subgraphSize := riddleCopy.GetConnectedSubgraphSize(adjacentNode)
-			// fmt.Printf("Subgraph size: %d\n", subgraphSize)
-			if subgraphSize < minimumRemainingSubgraphSizeForCurrentNode {
-				return false
-			}
+	for _, adjacentNode := range emptyAdjacentNodes {
+		if !riddle.islandSizeAtLeast(adjacentNode, minimumRemainingSubgraphSizeForCurrentNode) {
+			return false
 		}
 	}
 
@@ -549,88 +619,12 @@ func (riddle *Riddle) GetEdgesForWord(word *RiddleWord) []*LetterEdge {
 	return edges
 }
 
-func (riddle *Riddle) CheckForAmbiguity() (bool, [][]*LetterEdge) {
-	// for each word, check if it has more than one way to be filled on the board
-	for _, word := range riddle.Words {
-		if !word.Used && !word.IsSuperSolution {
-			continue
-		}
-		wordLength := word.Length()
-		if wordLength == 0 {
-			continue
-		}
-		var wordNodes = []*Node{}
-		for _, node := range riddle.Nodes {
-			if node.RiddleWord != nil && node.RiddleWord.Word == word.Word {
-				wordNodes = append(wordNodes, node)
-			}
-		}
-		var problematicSolutions = [][]*LetterEdge{}
-		// start with first letter and find all nodes that have this letter
-		var startingNodes []*Node
-		for _, node := range riddle.Nodes {
-			if node.RiddleWord != nil && node.RiddleWord.RuneAt(node.RiddleWordIndex) == word.RuneAt(0) {
-				startingNodes = append(startingNodes, node)
-			}
-		}
-		// for each starting node, find all possible paths that would fill the word
-		// only increase solutionCount if the path continues nodes outside of the actual word nodes
-		// this allows for words to be filled in multiple ways but not in a way that would create ambiguity
-		for _, startingNode := range startingNodes {
-			var paths = getPossiblePaths(riddle, word, startingNode, 0, []*Node{})
-			for _, path := range paths {
-				if len(path) < wordLength-1 {
-					continue
-				}
-				// check if path continues outside of the word nodes
-				for _, edge := range path {
-					// check if edge.Node1 or edge.Node2 is not in wordNodes
-					if !ContainsNodePosition(wordNodes, edge.Node1) || !ContainsNodePosition(wordNodes, edge.Node2) {
-						problematicSolutions = append(problematicSolutions, path)
-						break
-					}
-				}
-			}
-		}
-		if len(problematicSolutions) > 0 {
-			return true, problematicSolutions
-		}
-	}
-	return false, nil
-}
-
-func getPossiblePaths(riddle *Riddle, word *RiddleWord, node *Node, index int, nodesToIgnore []*Node) [][]*LetterEdge {
-	if index+1 == word.Length() {
-		return [][]*LetterEdge{}
-	}
-	nodesToIgnore = append(nodesToIgnore, node)
-	var nextLetter = word.RuneAt(index + 1)
-	var nextNodes = riddle.getAdjacentNodesWithLetter(node, nextLetter, nodesToIgnore)
-	var paths = [][]*LetterEdge{}
-	for _, nextNode := range nextNodes {
-		pathToNextNode := []*LetterEdge{{
-			Word:  word,
-			Node1: node,
-			Node2: nextNode,
-		}}
-		followingPaths := getPossiblePaths(riddle, word, nextNode, index+1, nodesToIgnore)
-		if len(followingPaths) == 0 {
-			paths = append(paths, pathToNextNode)
-		} else {
-			for _, followingPath := range followingPaths {
-				paths = append(paths, append(pathToNextNode, followingPath...))
-			}
-		}
-	}
-	return paths
-}
-
 func (riddle *Riddle) Render(debugOnly bool) {
 	if debugOnly && logrus.GetLevel() != logrus.DebugLevel {
 		return
 	}
-	for i := 0; i < RiddleHeight; i++ {
-		for j := 0; j < RiddleWidth; j++ {
+	for i := 0; i < riddle.Height; i++ {
+		for j := 0; j < riddle.Width; j++ {
 			var node = riddle.GetNode(i, j)
 			if node.RiddleWord == nil {
 				fmt.Print(" ")
@@ -644,7 +638,7 @@ func (riddle *Riddle) Render(debugOnly bool) {
 			fmt.Print("|") // Add vertical grid line after each cell
 		}
 		fmt.Println()
-		for j := 0; j < RiddleWidth; j++ {
+		for j := 0; j < riddle.Width; j++ {
 			fmt.Print("-") // Add horizontal grid line below each cell
 			fmt.Print("+") // Add intersection grid line
 		}
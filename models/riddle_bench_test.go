@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"straenge-riddle-worker/m/random"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sampleConcept is a riddle concept of the sample corpus in testdata/concepts.
+type sampleConcept struct {
+	Name    string
+	Concept RiddleConcept
+}
+
+func TestMain(m *testing.M) {
+	// the search logs every failed attempt
+	logrus.SetLevel(logrus.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+func loadSampleConcepts(tb testing.TB) []sampleConcept {
+	tb.Helper()
+	paths, err := filepath.Glob(filepath.Join("..", "testdata", "concepts", "*.json"))
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("no sample concepts found: %v", err)
+	}
+	var concepts []sampleConcept
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		var concept RiddleConcept
+		if err := json.Unmarshal(data, &concept); err != nil {
+			tb.Fatalf("%s: %v", path, err)
+		}
+		concepts = append(concepts, sampleConcept{Name: strings.TrimSuffix(filepath.Base(path), ".json"), Concept: concept})
+	}
+	return concepts
+}
+
+func newSampleRiddle(concept RiddleConcept, seed int64) (*Riddle, error) {
+	width, height := concept.GridSize()
+	return NewRiddle(context.Background(), random.NewSeededRand(seed), width, height, concept.SuperSolution, concept.WordPool)
+}
+
+// filledSampleRiddles returns up to count filled riddles of a concept, generated with consecutive seeds.
+func filledSampleRiddles(tb testing.TB, concept RiddleConcept, count int) []*Riddle {
+	tb.Helper()
+	var riddles []*Riddle
+	for seed := int64(1); seed <= 2000 && len(riddles) < count; seed++ {
+		riddle, err := newSampleRiddle(concept, seed)
+		if err != nil {
+			continue
+		}
+		riddle, err = riddle.FillWithWords(context.Background(), random.NewSeededRand(seed))
+		if err != nil {
+			continue
+		}
+		riddles = append(riddles, riddle)
+	}
+	if len(riddles) == 0 {
+		tb.Fatal("no riddle of the concept could be filled")
+	}
+	return riddles
+}
+
+func BenchmarkNewRiddle(b *testing.B) {
+	for _, sample := range loadSampleConcepts(b) {
+		b.Run(sample.Name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				newSampleRiddle(sample.Concept, int64(i))
+			}
+		})
+	}
+}
+
+func BenchmarkFillWithWords(b *testing.B) {
+	for _, sample := range loadSampleConcepts(b) {
+		b.Run(sample.Name, func(b *testing.B) {
+			riddle, err := newSampleRiddle(sample.Concept, 1)
+			if err != nil {
+				b.Fatal(err)
+			}
+			filled := 0
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := riddle.FillWithWords(context.Background(), random.NewSeededRand(int64(i))); err == nil {
+					filled++
+				}
+			}
+			b.ReportMetric(float64(filled)/float64(b.N), "filled/op")
+		})
+	}
+}
+
+func BenchmarkCheckForAmbiguity(b *testing.B) {
+	for _, sample := range loadSampleConcepts(b) {
+		b.Run(sample.Name, func(b *testing.B) {
+			riddles := filledSampleRiddles(b, sample.Concept, 8)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := riddles[i%len(riddles)].CheckForAmbiguity(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCountPartitions(b *testing.B) {
+	for _, sample := range loadSampleConcepts(b) {
+		b.Run(sample.Name, func(b *testing.B) {
+			riddles := filledSampleRiddles(b, sample.Concept, 8)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := riddles[i%len(riddles)].CountPartitions(context.Background(), 2); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"straenge-riddle-worker/m/random"
+)
+
+func TestIsEdgeReachable(t *testing.T) {
+	tests := []struct {
+		name                   string
+		node                   *Node
+		rowToReach, colToReach int
+		remainingSteps         int
+		want                   bool
+	}{
+		{"already on the row", node(7, 3), 7, -1, 0, true},
+		{"already on the column", node(2, 0), -1, 0, 0, true},
+		{"no steps left", node(6, 3), 7, -1, 0, false},
+		{"row in reach", node(3, 3), 7, -1, 4, true},
+		{"row out of reach", node(2, 3), 7, -1, 4, false},
+		{"row in reach upwards", node(4, 1), 0, -1, 4, true},
+		{"column in reach", node(4, 1), -1, 5, 4, true},
+		{"column out of reach", node(4, 0), -1, 5, 4, false},
+		{"row ignores the column distance", node(6, 0), 7, -1, 1, true},
+	}
+	for _, test := range tests {
+		if got := isEdgeReachable(test.node, test.rowToReach, test.colToReach, test.remainingSteps); got != test.want {
+			t.Errorf("%s: isEdgeReachable() = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+// TestGeneratedRiddleInvariants generates riddles of the sample concepts with many seeds
+// and checks every filled riddle for the rules of the game.
+func TestGeneratedRiddleInvariants(t *testing.T) {
+	seeds := int64(40)
+	if testing.Short() {
+		seeds = 10
+	}
+	for _, sample := range loadSampleConcepts(t) {
+		t.Run(sample.Name, func(t *testing.T) {
+			filled := 0
+			for seed := int64(1); seed <= seeds; seed++ {
+				riddle, err := newSampleRiddle(sample.Concept, seed)
+				if err != nil {
+					continue
+				}
+				checkRiddleInvariants(t, riddle, false, fmt.Sprintf("seed %d, super solution only", seed))
+				riddle, err = riddle.FillWithWords(context.Background(), random.NewSeededRand(seed))
+				if err != nil {
+					continue
+				}
+				filled++
+				checkRiddleInvariants(t, riddle, true, fmt.Sprintf("seed %d", seed))
+				// the placement itself is always a partition of the grid into the words
+				if partitions, err := riddle.CountPartitions(context.Background(), 2); err != nil || partitions == 0 {
+					t.Errorf("seed %d: CountPartitions() = %d, %v, want at least 1", seed, partitions, err)
+				}
+			}
+			if filled == 0 {
+				t.Fatalf("none of %d seeds filled a riddle", seeds)
+			}
+		})
+	}
+}
+
+// TestBlockedDiagonals checks the incrementally tracked diagonals against all edges of the board
+// while words are placed and rolled back.
+func TestBlockedDiagonals(t *testing.T) {
+	sample := loadSampleConcepts(t)[0]
+	for seed := int64(1); seed <= 10; seed++ {
+		riddle, err := newSampleRiddle(sample.Concept, seed)
+		if err != nil {
+			continue
+		}
+		rng := random.NewSeededRand(seed)
+		start := riddle.mark()
+		for _, word := range riddle.Words[1:] {
+			riddle.FillWord(context.Background(), rng, word, riddle.Nodes)
+			checkBlockedDiagonals(t, riddle, fmt.Sprintf("seed %d after %s", seed, word.Word))
+		}
+		riddle.undoTo(start)
+		checkBlockedDiagonals(t, riddle, fmt.Sprintf("seed %d after rollback", seed))
+		if len(riddle.Edges) != len(riddle.GetEdgesForWord(riddle.Words[0])) {
+			t.Errorf("seed %d: rollback kept %d edges of other words", seed, len(riddle.Edges)-len(riddle.GetEdgesForWord(riddle.Words[0])))
+		}
+	}
+}
+
+func checkBlockedDiagonals(t *testing.T, riddle *Riddle, description string) {
+	t.Helper()
+	for _, node := range riddle.Nodes {
+		for _, neighbor := range riddle.GetAdjacentNodes(node, true) {
+			want := true
+			candidate := &LetterEdge{Node1: node, Node2: neighbor}
+			for _, edge := range riddle.Edges {
+				if EdgesCross(candidate, edge) {
+					want = false
+					break
+				}
+			}
+			if got := riddle.DoesNotOverlapWithEdges(node, neighbor); got != want {
+				t.Fatalf("%s: DoesNotOverlapWithEdges(%s) = %t, want %t", description, describeEdge(candidate), got, want)
+			}
+		}
+	}
+}
+
+// checkRiddleInvariants checks that all used words are placed along adjacent cells in order, without crossing edges,
+// and that the super solution connects opposite edges. A complete riddle must also cover every cell.
+func checkRiddleInvariants(t *testing.T, riddle *Riddle, complete bool, description string) {
+	t.Helper()
+	if len(riddle.Nodes) != riddle.Width*riddle.Height {
+		t.Fatalf("%s: %d nodes for a %dx%d grid", description, len(riddle.Nodes), riddle.Width, riddle.Height)
+	}
+	if complete {
+		for _, node := range riddle.Nodes {
+			if node.RiddleWord == nil {
+				t.Errorf("%s: cell %d,%d is empty", description, node.Row, node.Col)
+			}
+		}
+	}
+	if HasOverlappingEdges(riddle.Edges) {
+		t.Errorf("%s: edges cross", description)
+	}
+	for _, word := range riddle.Words {
+		placed := make([]*Node, word.Length())
+		count := 0
+		for _, node := range riddle.Nodes {
+			if node.RiddleWord != word {
+				continue
+			}
+			count++
+			if node.RiddleWordIndex < 0 || node.RiddleWordIndex >= word.Length() || placed[node.RiddleWordIndex] != nil {
+				t.Errorf("%s: %s has an invalid or duplicate letter index %d at %d,%d", description, word.Word, node.RiddleWordIndex, node.Row, node.Col)
+				continue
+			}
+			placed[node.RiddleWordIndex] = node
+		}
+		if !word.Used {
+			if count > 0 {
+				t.Errorf("%s: unused word %s fills %d cells", description, word.Word, count)
+			}
+			continue
+		}
+		if count != word.Length() {
+			t.Errorf("%s: %s fills %d cells, want %d", description, word.Word, count, word.Length())
+			continue
+		}
+		edges := riddle.GetEdgesForWord(word)
+		if len(edges) != word.Length()-1 {
+			t.Errorf("%s: %s has %d edges, want %d", description, word.Word, len(edges), word.Length()-1)
+		}
+		locations := make([]LetterLocation, len(placed))
+		for i, node := range placed {
+			locations[i] = LetterLocation{Row: node.Row, Col: node.Col}
+			if i == 0 {
+				continue
+			}
+			if !locationsAreAdjacent(locations[i-1], locations[i]) {
+				t.Errorf("%s: letters %d and %d of %s are not adjacent", description, i-1, i, word.Word)
+			}
+			if i-1 < len(edges) && (edges[i-1].Node1 != placed[i-1] || edges[i-1].Node2 != node) {
+				t.Errorf("%s: edge %d of %s does not connect its letters %d and %d", description, i-1, word.Word, i-1, i)
+			}
+		}
+		if word.IsSuperSolution && !spansOppositeEdges(locations, riddle.Width, riddle.Height) {
+			t.Errorf("%s: super solution %s does not connect opposite edges", description, word.Word)
+		}
+	}
+}
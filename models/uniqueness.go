@@ -0,0 +1,269 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// cellSet is a bit set of cell indices of a riddle.
+type cellSet []uint64
+
+func newCellSet(size int) cellSet {
+	return make(cellSet, (size+63)/64)
+}
+
+func (set cellSet) add(index int) {
+	set[index/64] |= 1 << (index % 64)
+}
+
+func (set cellSet) has(index int) bool {
+	return set[index/64]&(1<<(index%64)) != 0
+}
+
+func (set cellSet) overlaps(other cellSet) bool {
+	for i := range set {
+		if set[i]&other[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (set cellSet) addAll(other cellSet) {
+	for i := range set {
+		set[i] |= other[i]
+	}
+}
+
+func (set cellSet) removeAll(other cellSet) {
+	for i := range set {
+		set[i] &^= other[i]
+	}
+}
+
+// firstMissing returns the lowest index below size that is not in the set, or -1 if all are.
+func (set cellSet) firstMissing(size int) int {
+	for i, word := range set {
+		if word != ^uint64(0) {
+			index := i*64 + bits.TrailingZeros64(^word)
+			if index < size {
+				return index
+			}
+			return -1
+		}
+	}
+	return -1
+}
+
+func (set cellSet) key() string {
+	key := make([]byte, 0, len(set)*8)
+	for _, word := range set {
+		for shift := 0; shift < 64; shift += 8 {
+			key = append(key, byte(word>>shift))
+		}
+	}
+	return string(key)
+}
+
+// partitionPath is a path of cells spelling a word, a candidate for a partition of the grid.
+// Paths of the same word over the same cells share a group, they only differ in the diagonals they draw.
+type partitionPath struct {
+	word  int
+	group int
+	cells cellSet
+	nodes []*Node
+}
+
+// partitionSearch is the state of CountPartitions.
+type partitionSearch struct {
+	riddle *Riddle
+	// candidate paths through every cell
+	pathsByCell [][]*partitionPath
+	// how often every word still has to be used
+	remaining []int
+	covered   cellSet
+	diagonals []uint8
+	placed    []*partitionPath
+	// partitions found so far, by the groups of their paths
+	partitions map[string]bool
+	count      int
+	limit      int
+}
+
+// CountPartitions counts the ways to cover every cell of the riddle with non-crossing paths of its placed words,
+// every word used as often as it is placed, and stops counting at limit.
+// Partitions are told apart by the cells of each word, reading the cells of a word in another order is the same partition.
+// Generated boards do not need it, CheckForAmbiguity already rejects every board with a second partition.
+func (riddle *Riddle) CountPartitions(ctx context.Context, limit int) (int, error) {
+	topology := riddle.grid()
+	search := &partitionSearch{
+		riddle:      riddle,
+		pathsByCell: make([][]*partitionPath, len(riddle.Nodes)),
+		covered:     newCellSet(len(riddle.Nodes)),
+		diagonals:   make([]uint8, len(riddle.diagonals)),
+		partitions:  map[string]bool{},
+		limit:       limit,
+	}
+
+	wordIndex := map[string]int{}
+	var words []*RiddleWord
+	letterCount := 0
+	for _, word := range riddle.Words {
+		if !word.Used || word.Length() == 0 {
+			continue
+		}
+		letterCount += word.Length()
+		if index, ok := wordIndex[word.Word]; ok {
+			search.remaining[index]++
+			continue
+		}
+		wordIndex[word.Word] = len(words)
+		words = append(words, word)
+		search.remaining = append(search.remaining, 1)
+	}
+	if letterCount != len(riddle.Nodes) {
+		return 0, nil
+	}
+
+	visited := make([]bool, len(riddle.Nodes))
+	groups := 0
+	for index, word := range words {
+		if err := checkCanceled(ctx); err != nil {
+			return 0, err
+		}
+		// a path is only a new candidate if it covers other cells or draws other diagonals,
+		// keeping one path per cell set could drop the only one that does not cross the other words
+		groupOfCells := map[string]int{}
+		seen := map[string]bool{}
+		riddle.forEachWordPath(word, visited, func(path []*Node) bool {
+			if pathCrossesItself(topology, path) {
+				return true
+			}
+			cells := newCellSet(len(riddle.Nodes))
+			diagonals := newCellSet(2 * len(riddle.diagonals))
+			for i, node := range path {
+				cells.add(node.Row*riddle.Width + node.Col)
+				if i > 0 {
+					if block, kind := topology.diagonalOf(path[i-1], node); kind != noDiagonal {
+						diagonals.add(2*block + int(kind) - 1)
+					}
+				}
+			}
+			cellsKey := cells.key()
+			key := cellsKey + diagonals.key()
+			if seen[key] {
+				return true
+			}
+			seen[key] = true
+			group, ok := groupOfCells[cellsKey]
+			if !ok {
+				group = groups
+				groupOfCells[cellsKey] = group
+				groups++
+			}
+			candidate := &partitionPath{word: index, group: group, cells: cells, nodes: append([]*Node(nil), path...)}
+			for _, node := range path {
+				cell := node.Row*riddle.Width + node.Col
+				search.pathsByCell[cell] = append(search.pathsByCell[cell], candidate)
+			}
+			return true
+		})
+	}
+
+	if err := search.run(ctx); err != nil {
+		return 0, err
+	}
+	return search.count, nil
+}
+
+// run covers the first uncovered cell with every candidate path that fits and recurses.
+func (search *partitionSearch) run(ctx context.Context) error {
+	if err := checkCanceled(ctx); err != nil {
+		return err
+	}
+	cell := search.covered.firstMissing(len(search.riddle.Nodes))
+	if cell == -1 {
+		search.addPartition()
+		return nil
+	}
+	for _, path := range search.pathsByCell[cell] {
+		if search.remaining[path.word] == 0 || path.cells.overlaps(search.covered) || search.crossesPlaced(path) {
+			continue
+		}
+		search.place(path, true)
+		err := search.run(ctx)
+		search.place(path, false)
+		if err != nil {
+			return err
+		}
+		if search.count >= search.limit {
+			return nil
+		}
+	}
+	return nil
+}
+
+// addPartition counts the placed paths as a partition, unless it only differs from a counted one in the order cells are read.
+func (search *partitionSearch) addPartition() {
+	groups := make([]int, len(search.placed))
+	for i, path := range search.placed {
+		groups[i] = path.group
+	}
+	sort.Ints(groups)
+	key := fmt.Sprint(groups)
+	if !search.partitions[key] {
+		search.partitions[key] = true
+		search.count++
+	}
+}
+
+func (search *partitionSearch) crossesPlaced(path *partitionPath) bool {
+	for i := 1; i < len(path.nodes); i++ {
+		block, kind := search.riddle.topology.diagonalOf(path.nodes[i-1], path.nodes[i])
+		if kind != noDiagonal && search.diagonals[block] != noDiagonal {
+			return true
+		}
+	}
+	return false
+}
+
+func (search *partitionSearch) place(path *partitionPath, placed bool) {
+	if placed {
+		search.covered.addAll(path.cells)
+		search.remaining[path.word]--
+		search.placed = append(search.placed, path)
+	} else {
+		search.covered.removeAll(path.cells)
+		search.remaining[path.word]++
+		search.placed = search.placed[:len(search.placed)-1]
+	}
+	for i := 1; i < len(path.nodes); i++ {
+		block, kind := search.riddle.topology.diagonalOf(path.nodes[i-1], path.nodes[i])
+		if kind == noDiagonal {
+			continue
+		}
+		if placed {
+			search.diagonals[block] = kind
+		} else {
+			search.diagonals[block] = noDiagonal
+		}
+	}
+}
+
+// pathCrossesItself reports whether two edges of the path are the two diagonals of the same 2x2 block.
+func pathCrossesItself(topology *gridTopology, path []*Node) bool {
+	blocks := map[int]bool{}
+	for i := 1; i < len(path); i++ {
+		block, kind := topology.diagonalOf(path[i-1], path[i])
+		if kind == noDiagonal {
+			continue
+		}
+		if blocks[block] {
+			return true
+		}
+		blocks[block] = true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCountPartitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		riddle *Riddle
+		limit  int
+		want   int
+	}{
+		{
+			name:   "unique",
+			riddle: riddleFromRows([]string{"HUND", "TIER"}),
+			limit:  10,
+			want:   1,
+		},
+		{
+			name:   "word with another path, but no other partition",
+			riddle: riddleFromRows([]string{"HUND", "OFEN"}),
+			limit:  10,
+			want:   1,
+		},
+		{
+			// AB and BA can each be read in both rows and in both columns
+			name:   "several partitions",
+			riddle: riddleFromRows([]string{"AB", "BA"}),
+			limit:  10,
+			want:   4,
+		},
+		{
+			name:   "stops at the limit",
+			riddle: riddleFromRows([]string{"AB", "BA"}),
+			limit:  2,
+			want:   2,
+		},
+		{
+			name: "crossing paths are no partition",
+			riddle: NewRiddleFromConfig(&RiddleConfig{
+				Letters: [][]string{{"A", "B"}, {"B", "A"}},
+				Solutions: []SolutionConfig{
+					{Word: "AA", Locations: []LetterLocation{{Row: 0, Col: 0}, {Row: 1, Col: 1}}},
+					{Word: "BB", Locations: []LetterLocation{{Row: 0, Col: 1}, {Row: 1, Col: 0}}},
+				},
+			}),
+			limit: 10,
+			want:  0,
+		},
+		{
+			// X G Y
+			// E V E
+			// U I Z
+			// ZIEGE can also be read with the two E swapped, but then I-E crosses U-V
+			name: "only one reading of a word does not cross the others",
+			riddle: NewRiddleFromConfig(&RiddleConfig{
+				Letters: [][]string{{"X", "G", "Y"}, {"E", "V", "E"}, {"U", "I", "Z"}},
+				Solutions: []SolutionConfig{
+					{Word: "ZIEGE", Locations: []LetterLocation{{Row: 2, Col: 2}, {Row: 2, Col: 1}, {Row: 1, Col: 2}, {Row: 0, Col: 1}, {Row: 1, Col: 0}}},
+					{Word: "UV", Locations: []LetterLocation{{Row: 2, Col: 0}, {Row: 1, Col: 1}}},
+					{Word: "X", Locations: []LetterLocation{{Row: 0, Col: 0}}},
+					{Word: "Y", Locations: []LetterLocation{{Row: 0, Col: 2}}},
+				},
+			}),
+			limit: 10,
+			want:  1,
+		},
+		{
+			name:   "unused pool words are not part of a partition",
+			riddle: riddleFromRows([]string{"HUND", "TIER"}, "Tun"),
+			limit:  10,
+			want:   1,
+		},
+	}
+	for _, test := range tests {
+		got, err := test.riddle.CountPartitions(context.Background(), test.limit)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: CountPartitions() = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCountPartitionsOfIncompleteRiddle(t *testing.T) {
+	riddle := riddleFromRows([]string{"HUND", "TIER"})
+	riddle.Words[1].Used = false
+	got, err := riddle.CountPartitions(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 0 {
+		t.Errorf("CountPartitions() = %d for a riddle with uncovered cells, want 0", got)
+	}
+}
+
+func TestCellSet(t *testing.T) {
+	set := newCellSet(130)
+	for _, index := range []int{0, 63, 64, 129} {
+		set.add(index)
+	}
+	if !set.has(63) || !set.has(64) || set.has(65) {
+		t.Error("has() does not match the added cells")
+	}
+	if got := set.firstMissing(130); got != 1 {
+		t.Errorf("firstMissing() = %d, want 1", got)
+	}
+	full := newCellSet(130)
+	for index := 0; index < 130; index++ {
+		full.add(index)
+	}
+	if got := full.firstMissing(130); got != -1 {
+		t.Errorf("firstMissing() of a full set = %d, want -1", got)
+	}
+	if !full.overlaps(set) {
+		t.Error("full set does not overlap")
+	}
+	full.removeAll(set)
+	if full.overlaps(set) || full.firstMissing(130) != 0 {
+		t.Error("removeAll() did not remove the cells")
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func node(row, col int) *Node {
+	return &Node{Row: row, Col: col}
+}
+
+func edge(row1, col1, row2, col2 int) *LetterEdge {
+	return &LetterEdge{Node1: node(row1, col1), Node2: node(row2, col2)}
+}
+
+func TestGetDirection(t *testing.T) {
+	tests := []struct {
+		p, q *Node
+		want string
+	}{
+		{node(2, 2), node(2, 3), "vertical"},
+		{node(2, 2), node(2, 1), "vertical"},
+		{node(2, 2), node(3, 2), "horizontal"},
+		{node(2, 2), node(1, 2), "horizontal"},
+		{node(2, 2), node(3, 3), "diagonal-type1-a"},
+		{node(2, 2), node(1, 1), "diagonal-type1-b"},
+		{node(2, 2), node(1, 3), "diagonal-type2-a"},
+		{node(2, 2), node(3, 1), "diagonal-type2-b"},
+	}
+	for _, test := range tests {
+		if got := GetDirection(test.p, test.q); got != test.want {
+			t.Errorf("GetDirection(%d,%d -> %d,%d) = %s, want %s", test.p.Row, test.p.Col, test.q.Row, test.q.Col, got, test.want)
+		}
+	}
+}
+
+func TestEdgesCross(t *testing.T) {
+	tests := []struct {
+		name         string
+		edge1, edge2 *LetterEdge
+		want         bool
+	}{
+		{"diagonals of a block", edge(1, 1, 2, 2), edge(2, 1, 1, 2), true},
+		{"diagonals of a block reversed", edge(2, 2, 1, 1), edge(1, 2, 2, 1), true},
+		{"same diagonal", edge(1, 1, 2, 2), edge(1, 1, 2, 2), false},
+		{"same diagonal reversed", edge(1, 1, 2, 2), edge(2, 2, 1, 1), false},
+		{"parallel diagonals", edge(1, 1, 2, 2), edge(1, 2, 2, 3), false},
+		{"diagonals of neighboring blocks", edge(1, 1, 2, 2), edge(2, 2, 1, 3), false},
+		{"diagonal and side of its block", edge(1, 1, 2, 2), edge(2, 1, 2, 2), false},
+		{"horizontal and vertical", edge(1, 1, 1, 2), edge(1, 1, 2, 1), false},
+	}
+	for _, test := range tests {
+		if got := EdgesCross(test.edge1, test.edge2); got != test.want {
+			t.Errorf("%s: EdgesCross() = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+// TestEdgesCrossGeometry compares EdgesCross with the drawn segments for all pairs of edges on a small grid.
+func TestEdgesCrossGeometry(t *testing.T) {
+	const size = 4
+	var edges []*LetterEdge
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			for _, offset := range neighborOffsets {
+				r, c := row+offset[0], col+offset[1]
+				if r >= 0 && r < size && c >= 0 && c < size {
+					edges = append(edges, edge(row, col, r, c))
+				}
+			}
+		}
+	}
+	for _, edge1 := range edges {
+		for _, edge2 := range edges {
+			if edgesShareNode(edge1, edge2) {
+				// such edges meet at the shared cell or lie on top of each other, which is never a crossing
+				if EdgesCross(edge1, edge2) {
+					t.Errorf("EdgesCross(%s, %s) = true for edges sharing a cell", describeEdge(edge1), describeEdge(edge2))
+				}
+				continue
+			}
+			want := segmentsIntersect(edge1, edge2)
+			if got := EdgesCross(edge1, edge2); got != want {
+				t.Errorf("EdgesCross(%s, %s) = %t, want %t", describeEdge(edge1), describeEdge(edge2), got, want)
+			}
+		}
+	}
+}
+
+func TestHasOverlappingEdges(t *testing.T) {
+	if HasOverlappingEdges([]*LetterEdge{edge(0, 0, 1, 1), edge(1, 1, 2, 2), edge(0, 1, 0, 2)}) {
+		t.Error("HasOverlappingEdges() = true for edges without crossing")
+	}
+	if !HasOverlappingEdges([]*LetterEdge{edge(0, 0, 0, 1), edge(0, 0, 1, 1), edge(1, 0, 0, 1)}) {
+		t.Error("HasOverlappingEdges() = false for crossing diagonals")
+	}
+}
+
+func edgesShareNode(edge1, edge2 *LetterEdge) bool {
+	for _, node1 := range []*Node{edge1.Node1, edge1.Node2} {
+		for _, node2 := range []*Node{edge2.Node1, edge2.Node2} {
+			if node1.Row == node2.Row && node1.Col == node2.Col {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// segmentsIntersect reports whether the segments between the cell centers of two edges intersect.
+func segmentsIntersect(edge1, edge2 *LetterEdge) bool {
+	orientation := func(a, b, c *Node) int {
+		value := (b.Col-a.Col)*(c.Row-a.Row) - (b.Row-a.Row)*(c.Col-a.Col)
+		switch {
+		case value > 0:
+			return 1
+		case value < 0:
+			return -1
+		}
+		return 0
+	}
+	o1 := orientation(edge1.Node1, edge1.Node2, edge2.Node1)
+	o2 := orientation(edge1.Node1, edge1.Node2, edge2.Node2)
+	o3 := orientation(edge2.Node1, edge2.Node2, edge1.Node1)
+	o4 := orientation(edge2.Node1, edge2.Node2, edge1.Node2)
+	// segments between neighboring cells without a shared cell never touch when collinear
+	return o1*o2 < 0 && o3*o4 < 0
+}
+
+func describeEdge(edge *LetterEdge) string {
+	return fmt.Sprintf("%d,%d->%d,%d", edge.Node1.Row, edge.Node1.Col, edge.Node2.Row, edge.Node2.Col)
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ValidateRiddleConfig checks a riddle config against the rules the generator guarantees
+// and returns a description of every violation found.
+func ValidateRiddleConfig(ctx context.Context, riddleConfig *RiddleConfig) ([]string, error) {
+	var problems []string
+	height := len(riddleConfig.Letters)
+	if height == 0 || len(riddleConfig.Letters[0]) == 0 {
+		return []string{"grid is empty"}, nil
+	}
+	width := len(riddleConfig.Letters[0])
+	for row, letters := range riddleConfig.Letters {
+		if len(letters) != width {
+			return []string{fmt.Sprintf("row %d has %d columns instead of %d", row, len(letters), width)}, nil
+		}
+	}
+	for _, solution := range riddleConfig.Solutions {
+		for _, location := range solution.Locations {
+			if location.Row < 0 || location.Row >= height || location.Col < 0 || location.Col >= width {
+				return []string{fmt.Sprintf("solution %s has location %d,%d outside of the grid", solution.Word, location.Row, location.Col)}, nil
+			}
+		}
+	}
+
+	// grid completeness: every cell has a letter and belongs to exactly one solution
+	usage := make([][]int, height)
+	for row := range usage {
+		usage[row] = make([]int, width)
+	}
+	for _, solution := range riddleConfig.Solutions {
+		for _, location := range solution.Locations {
+			usage[location.Row][location.Col]++
+		}
+	}
+	// the path checks read every word letter by letter along its locations and every cell as part of a word,
+	// they are skipped if a cell does not hold exactly one letter or is not part of any solution
+	incomplete := false
+	for row := 0; row < height; row++ {
+		for col := 0; col < width; col++ {
+			letter := riddleConfig.Letters[row][col]
+			if letters := utf8.RuneCountInString(MakeWordSafe(letter)); letters == 0 || letter == "?" {
+				problems = append(problems, fmt.Sprintf("cell %d,%d has no letter", row, col))
+				incomplete = true
+			} else if letters > 1 {
+				problems = append(problems, fmt.Sprintf("cell %d,%d has %d letters instead of 1", row, col, letters))
+				incomplete = true
+			}
+			if usage[row][col] == 0 {
+				problems = append(problems, fmt.Sprintf("cell %d,%d is not part of any solution", row, col))
+				incomplete = true
+			} else if usage[row][col] > 1 {
+				problems = append(problems, fmt.Sprintf("cell %d,%d is part of %d solutions", row, col, usage[row][col]))
+			}
+		}
+	}
+
+	// word adjacency: consecutive letters of a solution are neighbors on the grid
+	superSolutionCount := 0
+	for _, solution := range riddleConfig.Solutions {
+		if len(solution.Locations) == 0 {
+			problems = append(problems, fmt.Sprintf("solution %s has no locations", solution.Word))
+			continue
+		}
+		for i := 1; i < len(solution.Locations); i++ {
+			previous, current := solution.Locations[i-1], solution.Locations[i]
+			if !locationsAreAdjacent(previous, current) {
+				problems = append(problems, fmt.Sprintf("solution %s jumps from %d,%d to %d,%d", solution.Word, previous.Row, previous.Col, current.Row, current.Col))
+			}
+		}
+		if solution.IsSuperSolution {
+			superSolutionCount++
+			if !spansOppositeEdges(solution.Locations, width, height) {
+				problems = append(problems, fmt.Sprintf("super solution %s does not connect opposite edges of the grid", solution.Word))
+			}
+		}
+	}
+	if superSolutionCount != 1 {
+		problems = append(problems, fmt.Sprintf("riddle has %d super solutions instead of 1", superSolutionCount))
+	}
+
+	riddle := NewRiddleFromConfig(riddleConfig)
+	for i, edge1 := range riddle.Edges {
+		for _, edge2 := range riddle.Edges[i+1:] {
+			if EdgesCross(edge1, edge2) || EdgesCross(edge2, edge1) {
+				problems = append(problems, fmt.Sprintf("edge %d,%d-%d,%d of %s crosses edge %d,%d-%d,%d of %s",
+					edge1.Node1.Row, edge1.Node1.Col, edge1.Node2.Row, edge1.Node2.Col, edge1.Word.Word,
+					edge2.Node1.Row, edge2.Node1.Col, edge2.Node2.Row, edge2.Node2.Col, edge2.Word.Word))
+			}
+		}
+	}
+
+	if incomplete {
+		return problems, nil
+	}
+
+	partitions, err := riddle.CountPartitions(ctx, 2)
+	if err != nil {
+		return nil, err
+	}
+	if partitions > 1 {
+		problems = append(problems, "grid can be split into the solution words in more than one way")
+	}
+
+	alternatives, err := riddle.FindAlternativePlacements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, alternative := range alternatives {
+		problems = append(problems, alternative.String())
+	}
+	return problems, nil
+}
+
+func locationsAreAdjacent(a, b LetterLocation) bool {
+	rowDistance, colDistance := a.Row-b.Row, a.Col-b.Col
+	if a == b {
+		return false
+	}
+	return rowDistance >= -1 && rowDistance <= 1 && colDistance >= -1 && colDistance <= 1
+}
+
+// spansOppositeEdges mirrors the generator's rule for the super solution:
+// it starts on an edge of the grid and reaches the opposite edge.
+func spansOppositeEdges(locations []LetterLocation, width, height int) bool {
+	first := locations[0]
+	for _, location := range locations {
+		if first.Row == 0 && location.Row == height-1 ||
+			first.Row == height-1 && location.Row == 0 ||
+			first.Col == 0 && location.Col == width-1 ||
+			first.Col == width-1 && location.Col == 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// Z E L T
+// H U N D
+func validConfig() *RiddleConfig {
+	return &RiddleConfig{
+		Letters: [][]string{{"Z", "E", "L", "T"}, {"H", "U", "N", "D"}},
+		Solutions: []SolutionConfig{
+			{Word: "ZELT", IsSuperSolution: true, Locations: []LetterLocation{{Row: 0, Col: 0}, {Row: 0, Col: 1}, {Row: 0, Col: 2}, {Row: 0, Col: 3}}},
+			{Word: "HUND", Locations: []LetterLocation{{Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 1, Col: 2}, {Row: 1, Col: 3}}},
+		},
+	}
+}
+
+func TestValidateRiddleConfig(t *testing.T) {
+	problems, err := ValidateRiddleConfig(context.Background(), validConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("problems = %q for a valid config", problems)
+	}
+}
+
+func TestValidateRiddleConfigCellLetters(t *testing.T) {
+	tests := []struct {
+		letter string
+		want   []string
+	}{
+		{" ", []string{"cell 1,3 has no letter"}},
+		{"", []string{"cell 1,3 has no letter"}},
+		{"?", []string{"cell 1,3 has no letter"}},
+		{"DE", []string{"cell 1,3 has 2 letters instead of 1"}},
+	}
+	for _, test := range tests {
+		config := validConfig()
+		config.Letters[1][3] = test.letter
+		problems, err := ValidateRiddleConfig(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(problems, test.want) {
+			t.Errorf("cell %q: problems = %q, want %q", test.letter, problems, test.want)
+		}
+	}
+}
+
+func TestValidateRiddleConfigReportsReadingsWithOtherProblems(t *testing.T) {
+	// K U H E
+	// H U N D
+	config := validConfig()
+	config.Letters[0] = []string{"K", "U", "H", "E"}
+	config.Solutions[0].Word = "KUHE"
+	config.Solutions[0].IsSuperSolution = false
+	problems, err := ValidateRiddleConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"riddle has 0 super solutions instead of 1": false,
+		"HUND can also be read at 1,0 0,1 1,2 1,3":  false,
+	}
+	for _, problem := range problems {
+		if _, ok := want[problem]; ok {
+			want[problem] = true
+		}
+	}
+	for problem, found := range want {
+		if !found {
+			t.Errorf("problems = %q, missing %q", problems, problem)
+		}
+	}
+}
+
+func TestValidateRiddleConfigOverlappingSolutions(t *testing.T) {
+	config := validConfig()
+	// HUND continues into the last cell of ZELT, the path checks still run
+	config.Solutions[1].Locations = append(config.Solutions[1].Locations, LetterLocation{Row: 0, Col: 3})
+	problems, err := ValidateRiddleConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) == 0 || problems[0] != "cell 0,3 is part of 2 solutions" {
+		t.Errorf("problems = %q, want the overlapping cell first", problems)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	queueJobs       = "generate-riddle"
+	queueProcessing = "processing"
+	queueResults    = "generate-riddle-result"
+	queueFailed     = "generate-riddle-failed"
+)
+
+// finishScript removes a job from the processing list and runs the push of its outcome in the same step,
+// so a crash cannot lose the job in between. If the entry is gone, e.g. because a reaper requeued it,
+// nothing is pushed and the job is not published twice.
+var finishScript = redis.NewScript(`
+if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call(ARGV[2], KEYS[2], unpack(ARGV, 3))
+return 1
+`)
+
+// finishJob removes jobRaw from the processing list and runs command on destination with args,
+// e.g. LPUSH and the result. It returns false if the entry was no longer in the processing list.
+func finishJob(ctx context.Context, jobRaw string, command string, destination string, args ...any) (bool, error) {
+	finished, err := finishScript.Run(ctx, client, []string{queueProcessing, destination}, append([]any{jobRaw, command}, args...)...).Int()
+	if err != nil {
+		return false, err
+	}
+	if finished == 0 {
+		logrus.Warnf("Job is no longer in the %s list, it was requeued meanwhile and is not pushed to %s", queueProcessing, destination)
+	}
+	return finished == 1, nil
+}
+
+// handleJobFailure schedules a retry of a failed job,
+// or moves it to the dead-letter queue if it has no attempts left or retrying would not help.
+// Either way the job's entry jobRaw leaves the processing list in the same step.
+func handleJobFailure(ctx context.Context, cfg *workerConfig, jobRaw string, job models.Job, jobErr error, startedAt time.Time) {
+	errType, message := describeError(jobErr)
+	metricJobFailures.WithLabelValues(errType).Inc()
+	// retrying does not change the job or the concept, and an exhausted exact cover search has tried every board
+	if cfg.Retry.CanRetry(job.Attempt) && errType != models.ErrInvalidJob && errType != models.ErrInvalidConcept && errType != models.ErrConceptLint && errType != models.ErrExhausted {
+		metricJobs.WithLabelValues(jobResultRetried).Inc()
+		if err := scheduleRetry(ctx, cfg.Retry, jobRaw, job); err != nil {
+			logrus.Errorf("❌ Job could not be scheduled for retry: %v", err)
+		}
+		return
+	}
+	metricJobs.WithLabelValues(jobResultFailed).Inc()
+	failure := models.JobFailure{
+		Job:           job,
+		ErrType:       errType,
+		Message:       message,
+		Attempts:      job.Attempt + 1,
+		StartedAt:     startedAt,
+		FinishedAt:    time.Now().UTC(),
+		ParallelCount: cfg.ParallelCount,
+	}
+	var lintErr *models.ConceptLintError
+	if errors.As(jobErr, &lintErr) {
+		failure.Lint = lintErr.Findings
+	}
+	pushDeadLetter(ctx, jobRaw, failure)
+}
+
+// pushDeadLetter moves the job's entry jobRaw from the processing list to the dead-letter queue.
+func pushDeadLetter(ctx context.Context, jobRaw string, failure models.JobFailure) {
+	failureJson, err := json.Marshal(failure)
+	if err != nil {
+		logrus.Errorf("❌ Failure could not be serialized: %v", err)
+		return
+	}
+	moved, err := finishJob(ctx, jobRaw, "LPUSH", queueFailed, failureJson)
+	if err != nil {
+		logrus.Errorf("Redis Error while moving job to queue %s: %v", queueFailed, err)
+		return
+	}
+	if moved {
+		logrus.Warnf("🪦 Job moved to queue %s (%s)", queueFailed, failure.ErrType)
+	}
+}
+
+func describeError(err error) (string, string) {
+	var riddleErr *models.RiddleError
+	if errors.As(err, &riddleErr) {
+		return riddleErr.ErrType, riddleErr.Message
+	}
+	return models.ErrInternal, err.Error()
+}
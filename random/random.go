@@ -6,12 +6,24 @@ import (
 	"math/rand" // for rand.New() and rand.Rand
 )
 
-func NewSafeRand() *rand.Rand {
+// NewSeed returns a random seed from a secure source.
+func NewSeed() int64 {
 	var b [8]byte
 	_, err := crand.Read(b[:])
 	if err != nil {
 		panic(err)
 	}
-	seed := int64(binary.LittleEndian.Uint64(b[:]))
+	return int64(binary.LittleEndian.Uint64(b[:]))
+}
+
+// NewSeededRand returns a random number generator that produces the same sequence for the same seed.
+func NewSeededRand(seed int64) *rand.Rand {
 	return rand.New(rand.NewSource(seed))
 }
+
+// DeriveSeed returns the seed of the attempt with the given index, based on the seed of a job.
+// Attempt 0 uses the job seed itself, so a reported attempt seed regenerates the same riddle on the first attempt.
+func DeriveSeed(seed int64, attempt int64) int64 {
+	const golden uint64 = 0x9E3779B97F4A7C15
+	return int64(uint64(seed) + uint64(attempt)*golden)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const leaseKeyPrefix = "processing-lease:"
+
+// The leases of all processing entries with the same payload share a sorted set,
+// each claimed entry holds its own member (a unique token) scored with its expiry in Redis server time.
+// Identical jobs processed at the same time therefore neither release nor hide each other's lease.
+
+// renewLeaseScript adds or extends the token's lease, ARGV[2] is 1 to only extend a lease the token still holds.
+var renewLeaseScript = redis.NewScript(`
+local now = redis.call("TIME")
+local nowMs = now[1] * 1000 + math.floor(now[2] / 1000)
+local expiry = redis.call("ZSCORE", KEYS[1], ARGV[1])
+if ARGV[2] == "1" and (expiry == false or tonumber(expiry) <= nowMs) then
+	return 0
+end
+redis.call("ZADD", KEYS[1], nowMs + ARGV[3], ARGV[1])
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", nowMs)
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[3])
+end
+return 1
+`)
+
+// releaseLeaseScript removes only the token's own lease.
+var releaseLeaseScript = redis.NewScript(`
+return redis.call("ZREM", KEYS[1], ARGV[1])
+`)
+
+// requeueScript moves an orphaned entry from the processing list back to the job queue (or the dead-letter queue),
+// unless every entry with its payload has a live lease in the meantime or another reaper was faster.
+var requeueScript = redis.NewScript(`
+local now = redis.call("TIME")
+local nowMs = now[1] * 1000 + math.floor(now[2] / 1000)
+local leases = redis.call("ZCOUNT", KEYS[3], "(" .. nowMs, "+inf")
+local entries = #redis.call("LPOS", KEYS[1], ARGV[1], "COUNT", 0)
+if entries <= leases then
+	return 0
+end
+redis.call("LREM", KEYS[1], 1, ARGV[1])
+redis.call("LPUSH", KEYS[2], ARGV[2])
+return 1
+`)
+
+// liveLeasesScript counts the leases of a payload that have not expired.
+var liveLeasesScript = redis.NewScript(`
+local now = redis.call("TIME")
+local nowMs = now[1] * 1000 + math.floor(now[2] / 1000)
+return redis.call("ZCOUNT", KEYS[1], "(" .. nowMs, "+inf")
+`)
+
+func leaseKey(jobRaw string) string {
+	sum := sha1.Sum([]byte(jobRaw))
+	return leaseKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// leaseToken identifies one claimed processing entry, the worker ID makes it readable when inspecting Redis.
+func leaseToken(workerID string) string {
+	return workerID + ":" + newNonce()
+}
+
+func defaultWorkerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return hostname + "-" + strconv.Itoa(os.Getpid())
+}
+
+// jobLease marks a job in the processing list as owned by this worker
+// and keeps the ownership alive with a heartbeat until it is released.
+type jobLease struct {
+	key   string
+	token string
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+func acquireLease(ctx context.Context, jobRaw string, workerID string, ttl time.Duration) *jobLease {
+	lease := &jobLease{
+		key:   leaseKey(jobRaw),
+		token: leaseToken(workerID),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if err := lease.renew(ctx, false, ttl); err != nil {
+		logrus.Errorf("Redis Error while acquiring lease: %v", err)
+	}
+	go func() {
+		defer close(lease.done)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lease.stop:
+				return
+			case <-ticker.C:
+				if err := lease.renew(ctx, true, ttl); err != nil {
+					logrus.Errorf("Redis Error while renewing lease: %v", err)
+				}
+			}
+		}
+	}()
+	return lease
+}
+
+func (lease *jobLease) release(ctx context.Context) {
+	close(lease.stop)
+	<-lease.done
+	if err := releaseLeaseScript.Run(ctx, client, []string{lease.key}, lease.token).Err(); err != nil {
+		logrus.Errorf("Redis Error while releasing lease: %v", err)
+	}
+}
+
+// renew extends the lease, if held is set only while the token still holds it.
+func (lease *jobLease) renew(ctx context.Context, held bool, ttl time.Duration) error {
+	onlyHeld := "0"
+	if held {
+		onlyHeld = "1"
+	}
+	renewed, err := renewLeaseScript.Run(ctx, client, []string{lease.key}, lease.token, onlyHeld, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if renewed == 0 {
+		logrus.Warn("Lease expired before it could be renewed, the job may be requeued by a reaper")
+	}
+	return nil
+}
+
+// reaper periodically scans the processing list for entries without a lease
+// and moves them back to the job queue with an increased attempt counter.
+// A payload has to be seen with more entries than leases in two consecutive sweeps before an entry is requeued,
+// so a worker that just claimed a job has time to acquire its lease.
+type reaper struct {
+	interval time.Duration
+	policy   retryPolicy
+	suspects map[string]bool
+}
+
+func newReaper(interval time.Duration, policy retryPolicy) *reaper {
+	return &reaper{
+		interval: interval,
+		policy:   policy,
+		suspects: map[string]bool{},
+	}
+}
+
+func (r *reaper) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.sweep(ctx); err != nil {
+				logrus.Errorf("Reaper Error: %v", err)
+			}
+		}
+	}
+}
+
+func (r *reaper) sweep(ctx context.Context) error {
+	entries, err := client.LRange(ctx, queueProcessing, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, jobRaw := range entries {
+		counts[jobRaw]++
+	}
+	suspects := map[string]bool{}
+	for jobRaw, count := range counts {
+		leases, err := liveLeasesScript.Run(ctx, client, []string{leaseKey(jobRaw)}).Int()
+		if err != nil {
+			return err
+		}
+		if count <= leases {
+			continue
+		}
+		if !r.suspects[jobRaw] {
+			suspects[jobRaw] = true
+			continue
+		}
+		for orphan := 0; orphan < count-leases; orphan++ {
+			destination, err := requeueOrphan(ctx, r.policy, jobRaw)
+			if err != nil {
+				return err
+			}
+			if destination == "" {
+				break
+			}
+			logrus.Warnf("♻️ Moved orphaned job from %s to %s", queueProcessing, destination)
+		}
+	}
+	r.suspects = suspects
+	return nil
+}
+
+// requeueOrphan moves an orphaned job back to the job queue with an increased attempt counter,
+// or to the dead-letter queue if the retry policy has no attempts left for it.
+// It returns the destination queue, or an empty string if the entry was not moved.
+func requeueOrphan(ctx context.Context, policy retryPolicy, jobRaw string) (string, error) {
+	destination := queueJobs
+	requeuedRaw := jobRaw
+	var job models.Job
+	if err := json.Unmarshal([]byte(jobRaw), &job); err == nil {
+		normalizeAttempt(&job)
+		if policy.CanRetry(job.Attempt) {
+			job.Attempt++
+			jobJson, err := json.Marshal(job)
+			if err != nil {
+				return "", fmt.Errorf("orphaned job could not be serialized: %v", err)
+			}
+			requeuedRaw = string(jobJson)
+		} else {
+			now := time.Now().UTC()
+			failureJson, err := json.Marshal(models.JobFailure{
+				Job:        job,
+				ErrType:    models.ErrOrphaned,
+				Message:    "job lost its lease while being processed",
+				Attempts:   job.Attempt + 1,
+				StartedAt:  now,
+				FinishedAt: now,
+			})
+			if err != nil {
+				return "", fmt.Errorf("orphaned job failure could not be serialized: %v", err)
+			}
+			destination = queueFailed
+			requeuedRaw = string(failureJson)
+		}
+	}
+	res, err := requeueScript.Run(ctx, client, []string{queueProcessing, destination, leaseKey(jobRaw)}, jobRaw, requeuedRaw).Int()
+	if err != nil {
+		return "", err
+	}
+	if res == 0 {
+		return "", nil
+	}
+	return destination, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const queueDelayed = "generate-riddle-delayed"
+
+// promoteScript moves all due jobs from the delayed sorted set back into the job queue,
+// stripping the nonce that keeps identical jobs apart in the set, see delayedMember.
+// Members without a nonce, delayed by older workers, are moved as they are.
+var promoteScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, 100)
+for _, member in ipairs(due) do
+	redis.call("ZREM", KEYS[1], member)
+	local jobRaw = string.match(member, "^%x+:(.*)$") or member
+	redis.call("LPUSH", KEYS[2], jobRaw)
+end
+return #due
+`)
+
+// retryPolicy decides how often a job is attempted, how long each attempt may run
+// and how long a failed job waits before it is attempted again.
+// Attempts are counted from 0, matching models.Job.Attempt.
+type retryPolicy struct {
+	MaxAttempts   int
+	Timeouts      []time.Duration
+	Delay         time.Duration
+	BackoffFactor float64
+}
+
+// normalizeAttempt maps jobs of the former "normal → retry" ladder onto attempt numbers.
+func normalizeAttempt(job *models.Job) {
+	if job.Type == "retry" && job.Attempt == 0 {
+		job.Attempt = 1
+	}
+}
+
+func (policy retryPolicy) TimeoutFor(attempt int) time.Duration {
+	if attempt >= len(policy.Timeouts) {
+		return policy.Timeouts[len(policy.Timeouts)-1]
+	}
+	return policy.Timeouts[attempt]
+}
+
+func (policy retryPolicy) MaxTimeout() time.Duration {
+	var maxTimeout time.Duration
+	for _, timeout := range policy.Timeouts {
+		maxTimeout = max(maxTimeout, timeout)
+	}
+	return maxTimeout
+}
+
+func (policy retryPolicy) CanRetry(attempt int) bool {
+	return attempt+1 < policy.MaxAttempts
+}
+
+// DelayBefore returns how long to wait before running the given attempt.
+func (policy retryPolicy) DelayBefore(attempt int) time.Duration {
+	if policy.Delay == 0 || attempt == 0 {
+		return 0
+	}
+	return time.Duration(float64(policy.Delay) * math.Pow(policy.BackoffFactor, float64(attempt-1)))
+}
+
+// scheduleRetry moves the job's entry jobRaw from the processing list back into the queue,
+// or into the delayed set if the policy asks for a delay.
+func scheduleRetry(ctx context.Context, policy retryPolicy, jobRaw string, job models.Job) error {
+	job.Type = "retry"
+	job.Attempt++
+	retryRaw, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	delay := policy.DelayBefore(job.Attempt)
+	if delay == 0 {
+		_, err = finishJob(ctx, jobRaw, "LPUSH", queueJobs, retryRaw)
+		return err
+	}
+	logrus.Infof("⏳ Retrying job in %s (attempt %d/%d)", delay, job.Attempt+1, policy.MaxAttempts)
+	_, err = finishJob(ctx, jobRaw, "ZADD", queueDelayed, time.Now().Add(delay).Unix(), delayedMember(string(retryRaw)))
+	return err
+}
+
+// delayedMember prefixes the job with a nonce, so identical jobs retried at the same time stay separate members.
+func delayedMember(jobRaw string) string {
+	return newNonce() + ":" + jobRaw
+}
+
+// newNonce returns a random hex string to tell apart entries with the same payload.
+func newNonce() string {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		logrus.Fatalf("Could not create nonce: %v", err)
+	}
+	return hex.EncodeToString(nonce)
+}
+
+// runDelayedQueue periodically moves due jobs from the delayed set to the job queue.
+func runDelayedQueue(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			now := strconv.FormatInt(time.Now().Unix(), 10)
+			promoted, err := promoteScript.Run(ctx, client, []string{queueDelayed, queueJobs}, now).Int()
+			if err != nil {
+				logrus.Errorf("Redis Error while promoting delayed jobs: %v", err)
+				continue
+			}
+			if promoted > 0 {
+				logrus.Infof("Moved %d delayed jobs to queue %s", promoted, queueJobs)
+			}
+		}
+	}
+}
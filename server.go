@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+// startHTTPServer serves the worker's monitoring endpoints until shutdownCtx is canceled.
+func startHTTPServer(shutdownCtx context.Context, addr string, health *loopHealth) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/healthz", handleHealthz(health))
+	mux.Handle("/readyz", handleReadyz(shutdownCtx))
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-shutdownCtx.Done()
+		server.Shutdown(context.Background())
+	}()
+	go func() {
+		logrus.Infof("Serving monitoring endpoints on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("HTTP server error: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// generationStats collects the outcome of repeated generations of a concept, see runStats.
+type generationStats struct {
+	runs int
+	// durations of the successful runs
+	durations []time.Duration
+	// failed runs by error type
+	failures map[string]int
+}
+
+func newGenerationStats() *generationStats {
+	return &generationStats{failures: map[string]int{}}
+}
+
+func (stats *generationStats) add(duration time.Duration, err error) {
+	stats.runs++
+	if err != nil {
+		errType, _ := describeError(err)
+		stats.failures[errType]++
+		return
+	}
+	stats.durations = append(stats.durations, duration)
+}
+
+func (stats *generationStats) successRate() float64 {
+	if stats.runs == 0 {
+		return 0
+	}
+	return float64(len(stats.durations)) / float64(stats.runs)
+}
+
+func (stats *generationStats) meanDuration() time.Duration {
+	if len(stats.durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, duration := range stats.durations {
+		total += duration
+	}
+	return total / time.Duration(len(stats.durations))
+}
+
+// percentileDuration returns the duration below or at which the given share of the successful runs finished (nearest rank).
+func (stats *generationStats) percentileDuration(percentile float64) time.Duration {
+	if len(stats.durations) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(stats.durations)
+	slices.Sort(sorted)
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+func (stats *generationStats) write(writer io.Writer) {
+	fmt.Fprintf(writer, "runs:           %d\n", stats.runs)
+	fmt.Fprintf(writer, "success rate:   %.1f%% (%d/%d)\n", stats.successRate()*100, len(stats.durations), stats.runs)
+	if len(stats.durations) > 0 {
+		fmt.Fprintf(writer, "time to riddle: mean %s, p95 %s\n", stats.meanDuration().Round(time.Millisecond), stats.percentileDuration(95).Round(time.Millisecond))
+	}
+	if len(stats.failures) == 0 {
+		return
+	}
+	errTypes := make([]string, 0, len(stats.failures))
+	for errType := range stats.failures {
+		errTypes = append(errTypes, errType)
+	}
+	// most frequent failures first
+	sort.Slice(errTypes, func(i, j int) bool {
+		if stats.failures[errTypes[i]] != stats.failures[errTypes[j]] {
+			return stats.failures[errTypes[i]] > stats.failures[errTypes[j]]
+		}
+		return errTypes[i] < errTypes[j]
+	})
+	fmt.Fprintln(writer, "failures:")
+	for _, errType := range errTypes {
+		fmt.Fprintf(writer, "  %-16s %d\n", errType+":", stats.failures[errType])
+	}
+}
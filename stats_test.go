@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+)
+
+func TestGenerationStats(t *testing.T) {
+	stats := newGenerationStats()
+	for i := 1; i <= 20; i++ {
+		stats.add(time.Duration(i)*time.Second, nil)
+	}
+	stats.add(time.Minute, &models.RiddleError{ErrType: models.ErrAmbiguity, Message: "ambiguous"})
+	stats.add(time.Minute, &models.RiddleError{ErrType: models.ErrAmbiguity, Message: "ambiguous"})
+	stats.add(time.Minute, &models.RiddleError{ErrType: models.ErrWordFill, Message: "no words"})
+	stats.add(time.Minute, errors.New("something else"))
+
+	if stats.runs != 24 {
+		t.Errorf("runs = %d, want 24", stats.runs)
+	}
+	if rate := stats.successRate(); rate != 20.0/24.0 {
+		t.Errorf("successRate() = %f, want %f", rate, 20.0/24.0)
+	}
+	if mean := stats.meanDuration(); mean != 10500*time.Millisecond {
+		t.Errorf("meanDuration() = %s, want 10.5s", mean)
+	}
+	if p95 := stats.percentileDuration(95); p95 != 19*time.Second {
+		t.Errorf("percentileDuration(95) = %s, want 19s", p95)
+	}
+	want := map[string]int{models.ErrAmbiguity: 2, models.ErrWordFill: 1, models.ErrInternal: 1}
+	for errType, count := range want {
+		if stats.failures[errType] != count {
+			t.Errorf("failures[%s] = %d, want %d", errType, stats.failures[errType], count)
+		}
+	}
+
+	var report strings.Builder
+	stats.write(&report)
+	if !strings.Contains(report.String(), "success rate:   83.3% (20/24)") {
+		t.Errorf("report does not contain the success rate:\n%s", report.String())
+	}
+	if strings.Index(report.String(), models.ErrAmbiguity) > strings.Index(report.String(), models.ErrWordFill) {
+		t.Errorf("report does not list the most frequent failure first:\n%s", report.String())
+	}
+}
+
+func TestGenerationStatsWithoutRuns(t *testing.T) {
+	stats := newGenerationStats()
+	if stats.successRate() != 0 || stats.meanDuration() != 0 || stats.percentileDuration(95) != 0 {
+		t.Error("stats without runs are not zero")
+	}
+}
@@ -3,80 +3,286 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"straenge-riddle-worker/m/models"
+	"straenge-riddle-worker/m/random"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
-func processJob(ctx context.Context, job models.Job, parallelCount int) (*models.Riddle, error) {
+// attemptBudget limits the number of generation attempts running at the same time across all jobs.
+// A nil budget does not limit anything.
+type attemptBudget chan struct{}
+
+func newAttemptBudget(size int) attemptBudget {
+	return make(attemptBudget, size)
+}
+
+func (budget attemptBudget) acquire(ctx context.Context) error {
+	if budget == nil {
+		return nil
+	}
+	select {
+	case budget <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return &models.RiddleError{ErrType: models.ErrCanceled, Message: ctx.Err().Error()}
+	}
+}
+
+func (budget attemptBudget) release() {
+	if budget == nil {
+		return
+	}
+	<-budget
+}
+
+// limits for the grid size a riddle concept may ask for
+const (
+	minGridSize = 3
+	maxGridSize = 12
+)
+
+// minimum length of the dictionary words reported as bonus words, independent of the pool word lengths
+const bonusWordMinLength = 4
+
+// generation engines a job can choose, see models.Job
+const (
+	engineBacktracking = "backtracking"
+	engineExactCover   = "exact-cover"
+)
+
+// generationOptions are the settings of the generation that do not come from the riddle concept.
+type generationOptions struct {
+	// Engine is engineBacktracking (also if empty) or engineExactCover
+	Engine        string
+	ParallelCount int
+	Budget        attemptBudget
+	// optional word lists: bonus words of the dictionary are reported, boards with words of the blocklist are rejected
+	Dictionary *models.Dictionary
+	Blocklist  *models.Dictionary
+	// word length limits for concepts that do not set their own
+	DefaultWordLengths models.WordLengths
+	// Reproduce runs the first attempt alone, so a seed reported in a result gives back its riddle.
+	// Only set for a seed that was asked for, not for seeds that merely make runs repeatable.
+	Reproduce bool
+}
+
+// generationTask bundles the inputs of all generation attempts for one riddle concept.
+type generationTask struct {
+	generationOptions
+	Width         int
+	Height        int
+	SuperSolution string
+	// WordPool only contains the words within WordLengths
+	WordPool    []string
+	WordLengths models.WordLengths
+	// Seed is the seed of the first attempt, the seeds of further attempts are derived from it
+	Seed  int64
+	tries atomic.Int64
+}
+
+// generationResult is a generated riddle together with the seed of the attempt that produced it.
+type generationResult struct {
+	Riddle *models.Riddle
+	Seed   int64
+	// dictionary words that can be traced on the board but are not words of the concept
+	BonusWords []string
+	// lint warnings of the concept
+	Lint []models.LintFinding
+}
+
+func processJob(ctx context.Context, job models.Job, options generationOptions) (*generationResult, error) {
 	logrus.Infof("🛠 Processing Job: %s with payload: %s\n", job.Type, job.Payload)
 	// extract riddle concept from job payload
 	var riddleConcept models.RiddleConcept
 	err := json.Unmarshal([]byte(job.Payload), &riddleConcept)
 	if err != nil {
-		return nil, fmt.Errorf("error processing job: %v", err)
+		return nil, &models.RiddleError{ErrType: models.ErrInvalidConcept, Message: fmt.Sprintf("error processing job: %v", err)}
 	}
 
-	superSolution := riddleConcept.SuperSolution
-	wordPool := riddleConcept.WordPool
-	riddle := generateRiddle(ctx, superSolution, wordPool, parallelCount)
-	if riddle == nil {
+	options.Engine = job.Engine
+	options.Reproduce = job.Seed != nil
+	return generateFromConcept(ctx, riddleConcept, job.Seed, options)
+}
+
+// generateFromConcept runs the riddle generation for a concept until it succeeds or ctx expires.
+// Without a seed, a random one is used. Concepts with lint errors fail right away with a models.ConceptLintError.
+func generateFromConcept(ctx context.Context, riddleConcept models.RiddleConcept, seed *int64, options generationOptions) (*generationResult, error) {
+	if options.Engine != "" && options.Engine != engineBacktracking && options.Engine != engineExactCover {
+		return nil, &models.RiddleError{ErrType: models.ErrInvalidJob, Message: fmt.Sprintf("unknown engine %q, available engines: %s, %s", options.Engine, engineBacktracking, engineExactCover)}
+	}
+	width, height := riddleConcept.GridSize()
+	if width < minGridSize || height < minGridSize || width > maxGridSize || height > maxGridSize {
+		return nil, &models.RiddleError{ErrType: models.ErrInvalidConcept, Message: fmt.Sprintf("grid size %dx%d is not between %d and %d", width, height, minGridSize, maxGridSize)}
+	}
+	wordLengths := riddleConcept.WordLengths(options.DefaultWordLengths)
+	if !wordLengths.Valid() {
+		return nil, &models.RiddleError{ErrType: models.ErrInvalidConcept, Message: fmt.Sprintf("word lengths of %s are not possible", wordLengths)}
+	}
+	findings := models.LintConcept(riddleConcept, options.DefaultWordLengths)
+	for _, finding := range findings {
+		logrus.Warnf("Concept %s", finding)
+	}
+	if models.HasLintErrors(findings) {
+		return nil, &models.ConceptLintError{Findings: findings}
+	}
+	task := &generationTask{
+		generationOptions: options,
+		Width:             width,
+		Height:            height,
+		SuperSolution:     riddleConcept.SuperSolution,
+		WordPool:          wordLengths.Filter(riddleConcept.WordPool),
+		WordLengths:       wordLengths,
+		Seed:              random.NewSeed(),
+	}
+	if seed != nil {
+		task.Seed = *seed
+	}
+	var result *generationResult
+	var err error
+	if options.Engine == engineExactCover {
+		result, err = generateRiddleExactCover(ctx, task)
+	} else {
+		result, err = generateRiddle(ctx, task)
+	}
+	if err != nil {
 		logrus.Warn("Failed to generate riddle")
-		return nil, fmt.Errorf("failed to generate riddle")
+		return nil, err
 	}
-	return riddle, nil
+	result.Lint = findings
+	return result, nil
 }
 
-func generateRiddleSingleTry(superSolution string, wordPool []string) *models.Riddle {
-	logrus.Infof("Running riddle generation for super solution: %s", superSolution)
-	var riddle, err = models.NewRiddle(superSolution, wordPool)
+func generateRiddleSingleTry(ctx context.Context, task *generationTask) (*generationResult, error) {
+	if err := task.Budget.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer task.Budget.release()
+	seed := random.DeriveSeed(task.Seed, task.tries.Add(1)-1)
+	rng := random.NewSeededRand(seed)
+	logrus.Infof("Running riddle generation for super solution: %s (seed %d)", task.SuperSolution, seed)
+	var riddle, err = models.NewRiddle(ctx, rng, task.Width, task.Height, task.SuperSolution, task.WordPool)
+	if models.IsCanceled(err) {
+		logrus.Debug("Riddle generation canceled while placing super solution")
+		return nil, err
+	}
 	if err != nil {
 		logrus.Warn("Failed to create starting riddle")
 		logrus.Warn(err)
-		return nil
+		return nil, err
 	}
 	riddle.Render(true)
-	riddle, err = riddle.FillWithWords()
+	riddle, err = riddle.FillWithWords(ctx, rng)
+	if models.IsCanceled(err) {
+		logrus.Debug("Riddle generation canceled while filling words")
+		return nil, err
+	}
 	if err != nil {
 		logrus.Warn("Failed to fill riddle with words")
 		logrus.Warn(err)
-		return nil
+		return nil, err
+	}
+	return checkGeneratedRiddle(ctx, task, riddle, seed)
+}
+
+// checkGeneratedRiddle accepts a filled board if it has a single reading and passes the word lists.
+// A single reading also means the board splits into its words in only one way, see models.Riddle.CountPartitions.
+func checkGeneratedRiddle(ctx context.Context, task *generationTask, riddle *models.Riddle, seed int64) (*generationResult, error) {
+	var ambiguous, alternatives, ambiguityErr = riddle.CheckForAmbiguity(ctx)
+	if ambiguityErr != nil {
+		logrus.Debug("Riddle generation canceled while checking for ambiguity")
+		return nil, ambiguityErr
 	}
-	var ambiguous, _ = riddle.CheckForAmbiguity()
 	if ambiguous {
-		logrus.Warn("Generated riddle is ambiguous")
-		return nil
+		logrus.Warnf("Generated riddle is ambiguous, %d alternative placements", len(alternatives))
+		return nil, &models.RiddleError{ErrType: models.ErrAmbiguity, Message: fmt.Sprintf("Generated riddle is ambiguous: %s", alternatives[0])}
+	}
+	result := &generationResult{Riddle: riddle, Seed: seed}
+	if err := checkWordLists(ctx, task, result); err != nil {
+		return nil, err
 	}
 	logrus.Info("Riddle generation successful")
-	return riddle
+	return result, nil
 }
 
-func tryRiddleGenerationInParallel(superSolution string, wordPool []string, parallelCount int) *models.Riddle {
-	if parallelCount <= 1 {
+// checkWordLists rejects a finished board with a word of the blocklist and collects its bonus words from the dictionary.
+func checkWordLists(ctx context.Context, task *generationTask, result *generationResult) error {
+	if task.Blocklist != nil {
+		blockedWords, err := result.Riddle.FindDictionaryWords(ctx, task.Blocklist, 1)
+		if err != nil {
+			return err
+		}
+		if len(blockedWords) > 0 {
+			logrus.Warnf("Generated riddle contains %d words of the blocklist", len(blockedWords))
+			return &models.RiddleError{ErrType: models.ErrBlockedWord, Message: fmt.Sprintf("Generated riddle contains blocked words: %s", strings.Join(blockedWords, ", "))}
+		}
+	}
+	if task.Dictionary != nil {
+		dictionaryWords, err := result.Riddle.FindDictionaryWords(ctx, task.Dictionary, bonusWordMinLength)
+		if err != nil {
+			return err
+		}
+		conceptWords := map[string]bool{}
+		for _, word := range result.Riddle.Words {
+			conceptWords[word.Word] = true
+		}
+		for _, word := range dictionaryWords {
+			if !conceptWords[word] {
+				result.BonusWords = append(result.BonusWords, word)
+			}
+		}
+	}
+	return nil
+}
+
+// tryRiddleGenerationInParallel returns the first generated riddle,
+// or the error of the last failed goroutine if none of them succeeded.
+func tryRiddleGenerationInParallel(ctx context.Context, task *generationTask) (*generationResult, error) {
+	if task.ParallelCount <= 1 {
 		logrus.Info("Parallel count is 1 or less, running single generation")
-		return generateRiddleSingleTry(superSolution, wordPool)
+		result, err := generateRiddleSingleTry(ctx, task)
+		observeAttempt(err)
+		return result, err
 	}
 
-	logrus.Infof("Starting riddle generation in parallel with %d goroutines", parallelCount)
+	logrus.Infof("Starting riddle generation in parallel with %d goroutines", task.ParallelCount)
+
+	// cancel the remaining goroutines as soon as one of them found a riddle
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	var wg sync.WaitGroup
-	resultChan := make(chan *models.Riddle, 1)
+	var errMutex sync.Mutex
+	var lastErr error
+	resultChan := make(chan *generationResult, 1)
 
 	// Function to run in parallel
-	for i := 0; i < parallelCount; i++ {
+	for i := 0; i < task.ParallelCount; i++ {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
-			res := generateRiddleSingleTry(superSolution, wordPool)
-			if res != nil {
-				select {
-				case resultChan <- res:
-					// Signal found result
-				default:
-					// Ignore if result already sent
+			res, err := generateRiddleSingleTry(ctx, task)
+			observeAttempt(err)
+			if err != nil {
+				errMutex.Lock()
+				if lastErr == nil || !models.IsCanceled(err) {
+					lastErr = err
 				}
+				errMutex.Unlock()
+				return
+			}
+			select {
+			case resultChan <- res:
+				// Signal found result and stop the siblings
+				cancel()
+			default:
+				// Ignore if result already sent
 			}
 		}(i)
 	}
@@ -89,21 +295,98 @@ func tryRiddleGenerationInParallel(superSolution string, wordPool []string, para
 
 	// Wait for the first non-nil result or completion
 	if result, ok := <-resultChan; ok {
-		return result
+		return result, nil
 	}
 
-	return nil
+	return nil, lastErr
 }
 
-func generateRiddle(ctx context.Context, superSolution string, wordPool []string, parallelCount int) *models.Riddle {
-	for i := 0; ; i++ {
+// generateRiddleExactCover runs one systematic exact cover search seeded with the task's seed instead of random attempts.
+// Every board the search finds counts as an attempt and is checked like the boards of generateRiddleSingleTry.
+// The search holds one slot of the attempt budget, which it hands to waiting jobs after every rejected board
+// and after every turn of a super solution placement, as a turn may drop its partial boards without finding one.
+func generateRiddleExactCover(ctx context.Context, task *generationTask) (*generationResult, error) {
+	if err := task.Budget.acquire(ctx); err != nil {
+		return nil, err
+	}
+	holdsSlot := true
+	defer func() {
+		if holdsSlot {
+			task.Budget.release()
+		}
+	}()
+	handOverSlot := func() error {
+		// a waiting job takes the slot first, as its send was queued before this acquire
+		task.Budget.release()
+		holdsSlot = false
+		if err := task.Budget.acquire(ctx); err != nil {
+			return err
+		}
+		holdsSlot = true
+		return nil
+	}
+	startedAt := time.Now()
+	logrus.Infof("Running exact cover search for super solution: %s (seed %d)", task.SuperSolution, task.Seed)
+	var result *generationResult
+	_, err := models.NewRiddleExactCover(ctx, random.NewSeededRand(task.Seed), task.Width, task.Height, task.SuperSolution, task.WordPool, task.Blocklist, func(riddle *models.Riddle) error {
+		task.tries.Add(1)
+		var err error
+		result, err = checkGeneratedRiddle(ctx, task, riddle, task.Seed)
+		observeAttempt(err)
+		if err != nil {
+			if handOverErr := handOverSlot(); handOverErr != nil {
+				return handOverErr
+			}
+		}
+		return err
+	}, handOverSlot)
+	if err != nil {
+		if ctx.Err() != nil {
+			logrus.Warn("Reached Timeout, stopping exact cover search")
+			return nil, &models.RiddleError{ErrType: models.ErrTimeout, Message: fmt.Sprintf("reached timeout after %d boards", task.tries.Load())}
+		}
+		return nil, err
+	}
+	metricAttemptsPerSuccess.Observe(float64(task.tries.Load()))
+	metricTimeToRiddle.Observe(time.Since(startedAt).Seconds())
+	return result, nil
+}
+
+func generateRiddle(ctx context.Context, task *generationTask) (*generationResult, error) {
+	startedAt := time.Now()
+	var lastErr error
+	if task.Reproduce {
+		// parallel attempts would return whichever finishes first, not necessarily the one with the requested seed
+		result, err := generateRiddleSingleTry(ctx, task)
+		observeAttempt(err)
+		if err == nil {
+			metricAttemptsPerSuccess.Observe(float64(task.tries.Load()))
+			metricTimeToRiddle.Observe(time.Since(startedAt).Seconds())
+			return result, nil
+		}
+		if !models.IsCanceled(err) {
+			lastErr = err
+		}
+		logrus.Info("Attempt with the requested seed failed, continuing with derived seeds...")
+	}
+	for {
 		if ctx.Err() != nil {
 			logrus.Warn("Reached Timeout, stopping riddle generation")
-			return nil
+			tries := task.tries.Load()
+			var riddleErr *models.RiddleError
+			if lastErr == nil || !errors.As(lastErr, &riddleErr) {
+				return nil, &models.RiddleError{ErrType: models.ErrTimeout, Message: fmt.Sprintf("reached timeout after %d tries", tries)}
+			}
+			return nil, &models.RiddleError{ErrType: riddleErr.ErrType, Message: fmt.Sprintf("reached timeout after %d tries, last error: %s", tries, riddleErr.Message)}
+		}
+		result, err := tryRiddleGenerationInParallel(ctx, task)
+		if err == nil {
+			metricAttemptsPerSuccess.Observe(float64(task.tries.Load()))
+			metricTimeToRiddle.Observe(time.Since(startedAt).Seconds())
+			return result, nil
 		}
-		riddle := tryRiddleGenerationInParallel(superSolution, wordPool, parallelCount)
-		if riddle != nil {
-			return riddle
+		if !models.IsCanceled(err) {
+			lastErr = err
 		}
 		logrus.Info("Retrying riddle generation...")
 	}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"straenge-riddle-worker/m/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMain(m *testing.M) {
+	// the generation logs every failed attempt
+	logrus.SetLevel(logrus.ErrorLevel)
+	os.Exit(m.Run())
+}
+
+// sampleJob is a job for a riddle concept of the sample corpus in testdata/concepts.
+type sampleJob struct {
+	Name string
+	Job  models.Job
+}
+
+func loadSampleJobs(tb testing.TB) []sampleJob {
+	tb.Helper()
+	paths, err := filepath.Glob(filepath.Join("testdata", "concepts", "*.json"))
+	if err != nil || len(paths) == 0 {
+		tb.Fatalf("no sample concepts found: %v", err)
+	}
+	var jobs []sampleJob
+	for _, path := range paths {
+		var riddleConcept models.RiddleConcept
+		if err := readJsonFile(path, &riddleConcept); err != nil {
+			tb.Fatalf("%s: %v", path, err)
+		}
+		payload, err := json.Marshal(riddleConcept)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		jobs = append(jobs, sampleJob{
+			Name: strings.TrimSuffix(filepath.Base(path), ".json"),
+			Job:  models.Job{Type: "generate", Payload: string(payload)},
+		})
+	}
+	return jobs
+}
+
+func BenchmarkProcessJob(b *testing.B) {
+	for _, sample := range loadSampleJobs(b) {
+		b.Run(sample.Name, func(b *testing.B) {
+			failed := 0
+			for i := 0; i < b.N; i++ {
+				job := sample.Job
+				seed := int64(i + 1)
+				job.Seed = &seed
+				ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+				if _, err := processJob(ctx, job, generationOptions{ParallelCount: 1}); err != nil {
+					failed++
+				}
+				cancel()
+			}
+			b.ReportMetric(float64(failed)/float64(b.N), "failed/op")
+		})
+	}
+}
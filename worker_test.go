@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"straenge-riddle-worker/m/convert"
+	"straenge-riddle-worker/m/models"
+)
+
+// H U N D
+// T I E R
+func wordListRiddle() *models.Riddle {
+	return models.NewRiddleFromConfig(&models.RiddleConfig{
+		Letters: [][]string{{"H", "U", "N", "D"}, {"T", "I", "E", "R"}},
+		Solutions: []models.SolutionConfig{
+			{Word: "HUND", Locations: []models.LetterLocation{{Row: 0, Col: 0}, {Row: 0, Col: 1}, {Row: 0, Col: 2}, {Row: 0, Col: 3}}},
+			{Word: "TIER", Locations: []models.LetterLocation{{Row: 1, Col: 0}, {Row: 1, Col: 1}, {Row: 1, Col: 2}, {Row: 1, Col: 3}}},
+		},
+	})
+}
+
+func TestCheckWordListsBonusWords(t *testing.T) {
+	task := &generationTask{generationOptions: generationOptions{
+		Dictionary: models.NewDictionary([]string{"Hund", "Hier", "Hunde", "Tun", "Hut"}),
+	}, WordLengths: models.WordLengths{Min: 5}}
+	result := &generationResult{Riddle: wordListRiddle()}
+	if err := checkWordLists(context.Background(), task, result); err != nil {
+		t.Fatal(err)
+	}
+	// HUND is a word of the concept, TUN and HUT are too short, HIER is reported although pool words need 5 letters
+	want := []string{"HIER", "HUNDE"}
+	if !reflect.DeepEqual(result.BonusWords, want) {
+		t.Errorf("BonusWords = %q, want %q", result.BonusWords, want)
+	}
+}
+
+func TestCheckWordListsBlocklist(t *testing.T) {
+	task := &generationTask{generationOptions: generationOptions{
+		Blocklist: models.NewDictionary([]string{"Hut"}),
+	}}
+	err := checkWordLists(context.Background(), task, &generationResult{Riddle: wordListRiddle()})
+	var riddleErr *models.RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != models.ErrBlockedWord {
+		t.Errorf("err = %v, want a %s", err, models.ErrBlockedWord)
+	}
+
+	task.Blocklist = models.NewDictionary([]string{"Hieb"})
+	if err := checkWordLists(context.Background(), task, &generationResult{Riddle: wordListRiddle()}); err != nil {
+		t.Errorf("err = %v for a board without blocked words", err)
+	}
+}
+
+func TestGenerateFromConceptWordLengths(t *testing.T) {
+	// BAUERNHOF leaves 7 cells on a 4x4 grid, only KUH and HUND fill them
+	concept := models.RiddleConcept{SuperSolution: "Bauernhof", WordPool: []string{"Kuh", "Hund"}, Width: 4, Height: 4}
+	seed := int64(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := generateFromConcept(ctx, concept, &seed, generationOptions{ParallelCount: 1})
+	var lintErr *models.ConceptLintError
+	if !errors.As(err, &lintErr) {
+		t.Errorf("err = %v with 3 letter words below the minimum, want a ConceptLintError", err)
+	}
+
+	concept.MinWordLength = 3
+	result, err := generateFromConcept(ctx, concept, &seed, generationOptions{ParallelCount: 1})
+	if err != nil {
+		t.Fatalf("err = %v with 3 letter words allowed by the concept", err)
+	}
+	for _, word := range result.Riddle.Words {
+		if !word.Used {
+			t.Errorf("word %s is not placed", word.Word)
+		}
+	}
+
+	concept.MaxWordLength = 2
+	_, err = generateFromConcept(ctx, concept, &seed, generationOptions{ParallelCount: 1})
+	var riddleErr *models.RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != models.ErrInvalidConcept {
+		t.Errorf("err = %v for a maximum below the minimum, want a %s", err, models.ErrInvalidConcept)
+	}
+}
+
+func TestGenerateFromConceptExactCover(t *testing.T) {
+	concept := models.RiddleConcept{SuperSolution: "Bauernhof", WordPool: []string{"Kuh", "Hund"}, Width: 4, Height: 4, MinWordLength: 3}
+	seed := int64(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := generateFromConcept(ctx, concept, &seed, generationOptions{Engine: engineExactCover, ParallelCount: 1})
+	if err != nil {
+		t.Fatalf("err = %v with the exact cover engine", err)
+	}
+	if result.Seed != seed {
+		t.Errorf("Seed = %d, want %d", result.Seed, seed)
+	}
+	for _, word := range result.Riddle.Words {
+		if !word.Used {
+			t.Errorf("word %s is not placed", word.Word)
+		}
+	}
+
+	_, err = generateFromConcept(ctx, concept, &seed, generationOptions{Engine: "annealing", ParallelCount: 1})
+	var riddleErr *models.RiddleError
+	if !errors.As(err, &riddleErr) || riddleErr.ErrType != models.ErrInvalidJob {
+		t.Errorf("err = %v for an unknown engine, want a %s", err, models.ErrInvalidJob)
+	}
+}
+
+func TestGenerateFromConceptReproducesSeed(t *testing.T) {
+	var concept models.RiddleConcept
+	if err := readJsonFile("testdata/concepts/farm.json", &concept); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// parallel attempts report the seed of whichever attempt found the riddle
+	first, err := generateFromConcept(ctx, concept, nil, generationOptions{ParallelCount: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := first.Seed
+	width, height := concept.GridSize()
+	wordLengths := concept.WordLengths(models.WordLengths{})
+	task := &generationTask{
+		generationOptions: generationOptions{ParallelCount: 4, Reproduce: true},
+		Width:             width,
+		Height:            height,
+		SuperSolution:     concept.SuperSolution,
+		WordPool:          wordLengths.Filter(concept.WordPool),
+		WordLengths:       wordLengths,
+		Seed:              seed,
+	}
+	again, err := generateRiddle(ctx, task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the attempt with the requested seed runs alone, no other attempt can finish first
+	if tries := task.tries.Load(); tries != 1 {
+		t.Errorf("%d attempts to regenerate seed %d, want 1", tries, seed)
+	}
+	if again.Seed != seed {
+		t.Errorf("Seed = %d, want %d", again.Seed, seed)
+	}
+	want := convert.TransformToOutputFormat(first.Riddle, concept.ThemeDescription)
+	got := convert.TransformToOutputFormat(again.Riddle, concept.ThemeDescription)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("seed %d regenerated\n%v\nwant\n%v", seed, got.Letters, want.Letters)
+	}
+}
+
+func TestGenerateRiddleExactCoverSharesBudget(t *testing.T) {
+	var concept models.RiddleConcept
+	if err := readJsonFile("testdata/concepts/farm.json", &concept); err != nil {
+		t.Fatal(err)
+	}
+	budget := newAttemptBudget(1)
+	// every farm board contains an E, so the exact cover search drops all of them until its timeout
+	hardCtx, cancelHard := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelHard()
+	hardDone := make(chan error, 1)
+	go func() {
+		_, err := generateFromConcept(hardCtx, concept, nil, generationOptions{
+			Engine:    engineExactCover,
+			Budget:    budget,
+			Blocklist: models.NewDictionary([]string{"E"}),
+		})
+		hardDone <- err
+	}()
+	// the easy job must only start once the exact cover search holds the slot
+	deadline := time.Now().Add(time.Second)
+	for len(budget) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the exact cover search did not take the attempt slot")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	easy := models.RiddleConcept{SuperSolution: "Bauernhof", WordPool: []string{"Kuh", "Hund"}, Width: 4, Height: 4, MinWordLength: 3}
+	seed := int64(1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := generateFromConcept(ctx, easy, &seed, generationOptions{ParallelCount: 1, Budget: budget}); err != nil {
+		t.Errorf("err = %v while an exact cover search holds the only attempt slot", err)
+	}
+	cancelHard()
+	if err := <-hardDone; err == nil {
+		t.Error("the exact cover search accepted a board with a blocked word")
+	}
+}